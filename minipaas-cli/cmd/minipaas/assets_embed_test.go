@@ -3,7 +3,7 @@ package main
 import "testing"
 
 func TestReadEmbeddedSwarm_Success(t *testing.T) {
-	files := []string{"common.yml", "registry.yml", "caddy.yml", "caddy.json", "postgres.yaml"}
+	files := []string{"common.yml", "common-shared.yml", "registry.yml", "caddy.yml", "caddy.json", "postgres.yaml"}
 	for _, f := range files {
 		if _, err := readEmbeddedSwarm(f); err != nil {
 			t.Fatalf("readEmbeddedSwarm(%s) error: %v", f, err)
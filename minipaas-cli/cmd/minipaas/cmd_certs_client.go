@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 )
 
@@ -18,7 +17,7 @@ func (args *CertsClientArgs) Run() {
 
 	clientCertDir := filepath.Join(args.Env, cfg.Api.Certs)
 
-	err = os.MkdirAll(clientCertDir, 0755)
+	err = mkdirAll(clientCertDir, 0755)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to create output directory: %s", clientCertDir))
 
 	caCertFile := filepath.Join(args.CaDir, "ca.pem")
@@ -34,7 +33,7 @@ func (args *CertsClientArgs) Run() {
 
 	extFile := filepath.Join(clientCertDir, "extfile-client.cnf")
 	content := "extendedKeyUsage = clientAuth"
-	err = os.WriteFile(extFile, []byte(content), 0644)
+	err = writeFile(extFile, []byte(content), 0644)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to generate file: %s", extFile))
 
 	clientCertFile := filepath.Join(clientCertDir, "cert.pem")
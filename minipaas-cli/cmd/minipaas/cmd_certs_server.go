@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 )
 
@@ -15,7 +14,7 @@ type CertsServerArgs struct {
 func (args *CertsServerArgs) Run() {
 	fqdn := args.CN
 
-	err := os.MkdirAll(args.OutputDir, 0755)
+	err := mkdirAll(args.OutputDir, 0755)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to create output directory: %s", args.OutputDir))
 
 	serverCaKeyFile := filepath.Join(args.OutputDir, "ca-key.pem")
@@ -46,7 +45,7 @@ func (args *CertsServerArgs) Run() {
 	extFile := filepath.Join(args.OutputDir, "extfile.cnf")
 	content := fmt.Sprintf(`subjectAltName = DNS:%s
 extendedKeyUsage = serverAuth`, fqdn)
-	err = os.WriteFile(extFile, []byte(content), 0644)
+	err = writeFile(extFile, []byte(content), 0644)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to generate file: %s", extFile))
 
 	serverCertFile := filepath.Join(args.OutputDir, "server-cert.pem")
@@ -2,19 +2,25 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"slices"
 )
 
 type CodeInitArgs struct {
 	BaseArgs
-	Files  []string `arg:"-c,--compose-file,separate,required" help:"Compose files to load"`
-	Domain string   `arg:"-d,--domain" help:"Domain used for routing"`
-	Local  bool     `arg:"--local" help:"Use local Docker daemon" default:"true"`
+	Files    []string `arg:"-c,--compose-file,separate,required" help:"Compose files to load"`
+	Domain   string   `arg:"-d,--domain" help:"Domain used for routing"`
+	Local    bool     `arg:"--local" help:"Use local Docker daemon" default:"true"`
+	Stack    string   `arg:"--stack" help:"Swarm stack to deploy to; other than minipaas, it shares the Caddy and registry of the minipaas stack"`
+	Registry string   `arg:"--registry" help:"Registry the built images are pushed to (default registry:5000)"`
 }
 
 func (args *CodeInitArgs) Run() {
+	checkErrorPanic(validateStackName(args.Stack), "❌ Invalid --stack")
+	shared := args.Stack != "" && args.Stack != defaultStack
+
+	cfg := Config{Stack: args.Stack, Registry: args.Registry}
+
 	project, err := composeLoadProject(args.Files)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", args.Files))
 
@@ -26,7 +32,7 @@ func (args *CodeInitArgs) Run() {
 	}
 	appsCompose := buildDeployProject()
 	for name, svc := range project.Services {
-		appsCompose.Services[name] = createDeployService(svc, slices.Contains(imageNames, svc.Image))
+		appsCompose.Services[name] = createDeployService(svc, slices.Contains(imageNames, svc.Image), cfg.RegistryName())
 	}
 
 	var api ApiConfig
@@ -45,61 +51,72 @@ func (args *CodeInitArgs) Run() {
 		}
 	}
 
-	err = os.MkdirAll(args.Env, 0755)
+	err = mkdirAll(args.Env, 0755)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to create directory: %s", args.Env))
 
 	swarmAppsPath, err := saveProject(args.Env, appsCompose)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmAppsPath))
 	fmt.Println("✅ ", swarmAppsPath)
 
+	// A shared stack joins the network of the minipaas stack instead of
+	// running its own Caddy and registry.
+	commonEmbed := "common.yml"
+	if shared {
+		commonEmbed = "common-shared.yml"
+	}
 	swarmCommonFile := filepath.Join(args.Env, "compose.common.yml")
-	swarmCommonContent, err := readEmbeddedSwarm("common.yml")
-	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "common.yml"))
-	err = os.WriteFile(swarmCommonFile, swarmCommonContent, 0644)
+	swarmCommonContent, err := readEmbeddedSwarm(commonEmbed)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", commonEmbed))
+	err = writeFile(swarmCommonFile, swarmCommonContent, 0644)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmCommonFile))
 	fmt.Println("✅ ", swarmCommonFile)
 
-	swarmRegistryFile := filepath.Join(args.Env, "compose.registry.yml")
-	swarmRegistryContent, err := readEmbeddedSwarm("registry.yml")
-	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "registry.yml"))
-	err = os.WriteFile(swarmRegistryFile, swarmRegistryContent, 0644)
-	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmRegistryFile))
-	fmt.Println("✅ ", swarmRegistryFile)
+	files := append(args.Files, swarmCommonFile, swarmAppsPath)
+
+	if !shared {
+		swarmRegistryFile := filepath.Join(args.Env, "compose.registry.yml")
+		swarmRegistryContent, err := readEmbeddedSwarm("registry.yml")
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "registry.yml"))
+		err = writeFile(swarmRegistryFile, swarmRegistryContent, 0644)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmRegistryFile))
+		fmt.Println("✅ ", swarmRegistryFile)
+		files = append(files, swarmRegistryFile)
+	}
 
 	// optional Postgres service override file
 	swarmPostgresFile := filepath.Join(args.Env, "compose.postgres.yml")
 	swarmPostgresContent, err := readEmbeddedSwarm("postgres.yaml")
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "postgres.yaml"))
-	err = os.WriteFile(swarmPostgresFile, swarmPostgresContent, 0644)
+	err = writeFile(swarmPostgresFile, swarmPostgresContent, 0644)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmPostgresFile))
 	fmt.Println("✅ ", swarmPostgresFile)
+	files = append(files, swarmPostgresFile)
 
-	swarmCaddyFile := filepath.Join(args.Env, "compose.caddy.yml")
-	swarmCaddyContent, err := readEmbeddedSwarm("caddy.yml")
-	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "caddy.yml"))
-	err = os.WriteFile(swarmCaddyFile, swarmCaddyContent, 0644)
-	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmCaddyFile))
-	fmt.Println("✅ ", swarmCaddyFile)
+	if !shared {
+		swarmCaddyFile := filepath.Join(args.Env, "compose.caddy.yml")
+		swarmCaddyContent, err := readEmbeddedSwarm("caddy.yml")
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "caddy.yml"))
+		err = writeFile(swarmCaddyFile, swarmCaddyContent, 0644)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", swarmCaddyFile))
+		fmt.Println("✅ ", swarmCaddyFile)
+		files = append(files, swarmCaddyFile)
+	}
 
 	caddyConfFile := filepath.Join(args.Env, "caddy.json")
 	caddyConfContent, err := readEmbeddedSwarm("caddy.json")
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load embed file: %s", "caddy.json"))
-	err = os.WriteFile(caddyConfFile, caddyConfContent, 0644)
+	err = writeFile(caddyConfFile, caddyConfContent, 0644)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", caddyConfFile))
 	fmt.Println("✅ ", caddyConfFile)
 
-	files := append(args.Files, swarmCommonFile, swarmAppsPath, swarmRegistryFile, swarmPostgresFile, swarmCaddyFile)
-
-	mpFile := Config{
-		Project: ProjectConfig{
-			Files: files,
-		},
-		Api: api,
-		Deploy: DeployConfig{
-			Version: "0.1.0",
-		},
+	cfg.Project = ProjectConfig{
+		Files: files,
+	}
+	cfg.Api = api
+	cfg.Deploy = DeployConfig{
+		Version: "0.1.0",
 	}
-	mpPath, err := saveConfig(args.Env, mpFile)
+	mpPath, err := saveConfig(args.Env, cfg)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", mpPath))
 	fmt.Println("✅ ", mpPath)
 
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+)
+
+type CodeRetagArgs struct {
+	BaseArgs
+	Registry string `arg:"--registry" help:"Registry to switch to, saved in minipaas.yaml. Defaults to the configured one."`
+}
+
+func (args *CodeRetagArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load configuration file: %s", configFile))
+
+	if args.Registry != "" && args.Registry != cfg.Registry {
+		cfg.Registry = args.Registry
+		configFile, err = saveConfig(args.Env, cfg)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", configFile))
+		fmt.Println("✅ ", configFile)
+	}
+
+	deployProject, composeFile, err := loadProject(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load compose file: %s", composeFile))
+	source, err := loadSourceProject(args.Env, cfg)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load project files: %s", cfg.Project.Files))
+
+	retags := registryRetags(deployProject, source, cfg.RegistryName())
+	if len(retags) == 0 {
+		fmt.Printf("🔹 Images already use %s\n", cfg.RegistryName())
+		return
+	}
+	for _, r := range retags {
+		svc := deployProject.Services[r.Service]
+		svc.Image = r.To
+		deployProject.Services[r.Service] = svc
+		fmt.Printf("🔹 %s: %s -> %s\n", r.Service, r.From, r.To)
+	}
+
+	composeFile, err = saveProject(args.Env, deployProject)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write compose file: %s", composeFile))
+	fmt.Println("✅ ", composeFile)
+}
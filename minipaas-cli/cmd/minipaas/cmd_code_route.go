@@ -12,10 +12,13 @@ type CodeRouteArgs struct {
 }
 
 func (args *CodeRouteArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+
 	deployProject, composeFile, err := loadProject(args.Env)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load build file: %s", composeFile))
 
-	serverFile, err := caddyUpdateConfigAddRoute(args.Env, args.URL, args.Target)
+	serverFile, err := caddyUpdateConfigAddRoute(args.Env, cfg.StackName(), args.URL, args.Target)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
 	fmt.Println("✅ ", serverFile)
 
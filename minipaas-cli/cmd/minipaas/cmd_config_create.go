@@ -13,7 +13,15 @@ type ConfigCreateArgs struct {
 	BaseArgs
 	Name string   `arg:"--name" help:"Name of the Docker config to create. If not provided, a unique name is generated."`
 	File string   `arg:"positional" help:"Path to file to use for config content. If omitted, reads from STDIN."`
-	For  []string `arg:"--for,separate" help:"Containers that use the config"`
+	For  []string `arg:"--for,separate" help:"Containers that use the config, as service or service:target"`
+
+	Target string `arg:"--target" help:"Path the config is mounted at. Defaults to the config name."`
+	UID    string `arg:"--uid" help:"UID owning the mounted file"`
+	GID    string `arg:"--gid" help:"GID owning the mounted file"`
+	Mode   string `arg:"--mode" help:"Octal permissions of the mounted file, e.g. 0400"`
+
+	Template bool   `arg:"--template" help:"Render the content with Go text/template before creating the config" default:"false"`
+	Values   string `arg:"--values" help:"Values file for --template. Defaults to <env>/values.yaml when present."`
 }
 
 func (args *ConfigCreateArgs) Run() {
@@ -36,40 +44,39 @@ func (args *ConfigCreateArgs) Run() {
 		baseName = args.Name
 	}
 
+	if args.Template {
+		values, err := loadTemplateValues(args.Env, args.Values)
+		checkErrorPanic(err, "❌ Failed to load template values")
+		content, err = renderTemplate(baseName, content, newTemplateData(args.Env, cfg, values))
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to render template: %s", baseName))
+	}
+
+	mode, err := parseFileMode(args.Mode)
+	checkErrorPanic(err, "❌ Failed to parse --mode")
+	mounts, err := parseServiceMounts(args.For, serviceMount{Target: args.Target, UID: args.UID, GID: args.GID, Mode: mode})
+	checkErrorPanic(err, "❌ Failed to parse --for")
+
 	if len(content) == 0 {
 		log.Printf("⚠️ Input is empty, skipping.")
 		return
 	}
 
 	env := args.Env
-	service := args.For
 	verbose := args.Verbose
 
-	configName, err := configCreate(baseName, content, verbose)
+	configName, err := configCreate(cfg.StackName(), baseName, content, verbose)
 	checkErrorPanic(err, fmt.Sprintf("❌ Failed to create config for input: %s", baseName))
 	fmt.Printf("✅ Config created: %s\n", configName)
 
 	// Load project config to discover compose files in env
 	cfg, _, err = loadConfig(env)
 	checkErrorPanic(err, "❌ Failed to load MiniPaaS configuration")
-	orderedFiles := composeFilesForEnv(env, cfg)
 
-	// Group services by owning compose file
-	svcPerFile, missing := groupServicesByComposeFile(orderedFiles, service)
-	if len(missing) > 0 {
-		checkErrorPanic(fmt.Errorf("services not found: %v", missing), "❌ Failed to find services in compose files")
-	}
-
-	// Patch each compose file
-	for file, svcs := range svcPerFile {
-		project, _, lerr := loadComposeFile(file)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to load compose file: %s", file))
-
-		lerr = addComposeConfig(project, configName, baseName, svcs)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to update compose: %s", file))
-
-		_, lerr = saveComposeFile(file, project)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to update compose file: %s", file))
+	// Patch each compose file that owns at least one target service
+	orderedFiles := composeFilesForEnv(env, cfg)
+	changedFiles, err := updateComposeFilesAddConfig(orderedFiles, configName, baseName, mounts)
+	checkErrorPanic(err, "❌ Failed to update compose files")
+	for _, file := range changedFiles {
 		fmt.Printf("✅ Updated compose file with config: %s\n", file)
 	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+type ConfigDeleteArgs struct {
+	BaseArgs
+	Name string `arg:"--name,required" help:"Name of the config to delete. Either the base name or one of its hashed versions; every version is removed."`
+}
+
+func (args *ConfigDeleteArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	baseName := objectBaseName(args.Name)
+
+	// Unlabelled versions only belong to this stack while it references them,
+	// so collect the references before dropping them.
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	referenced, err := composeFilesConfigNames(orderedFiles)
+	checkErrorPanic(err, "❌ Failed to collect config references from compose files")
+
+	// Drop every reference first so the next rollout stops mounting it
+	changedFiles, err := updateComposeFilesRemoveConfig(orderedFiles, baseName)
+	checkErrorPanic(err, "❌ Failed to remove config from compose files")
+	for _, file := range changedFiles {
+		fmt.Printf("✅ Updated compose file: %s\n", file)
+	}
+
+	versions, err := configVersions(cfg.StackName(), baseName, referenced)
+	checkErrorPanic(err, "❌ Failed to list configs")
+	if len(versions) == 0 {
+		log.Printf("⚠️ No config found in Swarm for: %s", baseName)
+		return
+	}
+
+	failed := 0
+	for _, name := range versions {
+		if err = dockerConfigRemove(name, args.Verbose); err != nil {
+			fmt.Printf("❌ Failed to remove config %s (still used by a running service?): %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ Config removed: %s\n", name)
+	}
+	if failed > 0 {
+		checkErrorPanic(fmt.Errorf("%d of %d config(s) could not be removed", failed, len(versions)), "❌ Failed to delete config")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+type ConfigDetachArgs struct {
+	BaseArgs
+	Name string   `arg:"--name,required" help:"Name of the config to detach. A base name detaches all of its versions."`
+	From []string `arg:"--from,separate,required" help:"Services that should stop mounting the config"`
+}
+
+func (args *ConfigDetachArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load configuration file: %s", configFile))
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	changedFiles, err := updateComposeFilesDetachConfig(orderedFiles, args.Name, args.From)
+	checkErrorPanic(err, "❌ Failed to detach config from compose files")
+	if len(changedFiles) == 0 {
+		log.Printf("⚠️ %s is not mounted by %v, nothing to do.", args.Name, args.From)
+		return
+	}
+	for _, file := range changedFiles {
+		fmt.Printf("✅ Updated compose file: %s\n", file)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type ConfigListArgs struct {
+	BaseArgs
+}
+
+func (args *ConfigListArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	referenced, err := composeFilesConfigMounts(orderedFiles)
+	checkErrorPanic(err, "❌ Failed to collect config references from compose files")
+
+	inSwarm, err := dockerConfigList(nil)
+	checkErrorPanic(err, "❌ Failed to list configs")
+
+	entries := buildObjectInventory(referenced, inSwarm)
+	err = printObjectInventory(os.Stdout, entries)
+	checkErrorPanic(err, "❌ Failed to print configs")
+
+	if missing := countObjectStatus(entries, objectStatusMissing); missing > 0 {
+		fmt.Printf("⚠️ %d config version(s) referenced in compose are missing in Swarm: deploy rollout will fail\n", missing)
+	}
+	if unreferenced := countObjectStatus(entries, objectStatusUnreferenced); unreferenced > 0 {
+		fmt.Printf("⚠️ %d config version(s) in Swarm are not referenced: see config prune\n", unreferenced)
+	}
+}
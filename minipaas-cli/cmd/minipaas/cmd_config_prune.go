@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+)
+
+type ConfigPruneArgs struct {
+	BaseArgs
+	Delete bool `arg:"--delete" help:"Remove orphan configs from Swarm. Without it, orphans are only reported." default:"false"`
+}
+
+func (args *ConfigPruneArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	referenced, err := composeFilesConfigNames(composeFilesForEnv(args.Env, cfg))
+	checkErrorPanic(err, "❌ Failed to collect config references from compose files")
+
+	services, err := dockerServiceList()
+	checkErrorPanic(err, "❌ Failed to list Swarm services")
+	mounted := serviceMountedConfigs(services)
+
+	// Unlabelled versions predate the stack label; they belong to this stack
+	// when their logical name is one its compose files use.
+	existing, err := stackObjects(dockerConfigList, cfg.StackName(), referencedBaseName(referenced))
+	checkErrorPanic(err, "❌ Failed to list configs")
+
+	orphans := findOrphanObjects(existing, referenced, mounted)
+	if len(orphans) == 0 {
+		fmt.Println("✅ No orphan configs found")
+		return
+	}
+
+	for _, name := range orphans {
+		fmt.Printf("🔹 Orphan config: %s\n", name)
+	}
+	if !args.Delete {
+		fmt.Printf("⚠️ %d orphan config(s) found. Re-run with --delete to remove them.\n", len(orphans))
+		return
+	}
+
+	failed := 0
+	for _, name := range orphans {
+		if err = dockerConfigRemove(name, args.Verbose); err != nil {
+			fmt.Printf("❌ Failed to remove config %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ Config removed: %s\n", name)
+	}
+	if failed > 0 {
+		checkErrorPanic(fmt.Errorf("%d of %d config(s) could not be removed", failed, len(orphans)), "❌ Failed to prune configs")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type DeployBluegreenFinishArgs struct {
+	BaseArgs
+	Services []string      `arg:"positional,required" help:"Services whose blue/green deployment is completed."`
+	Timeout  time.Duration `arg:"--timeout" help:"How long to wait for each blue service to converge" default:"10m"`
+}
+
+func (args *DeployBluegreenFinishArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	composeFiles := deployComposeFiles(args.Env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", composeFiles))
+
+	// Keep whichever color is routed. When green is live, the stack service
+	// is updated to the compose spec green runs while it gets no traffic and
+	// routing goes back to it; either way the green service is removed.
+	stack := cfg.StackName()
+	routed := false
+	for _, service := range args.Services {
+		green := stackService(stack, greenService(service))
+		_, err := dockerServiceInspect(green)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to inspect service: %s", green))
+
+		live, err := greenIsLive(args.Env, stack, service)
+		checkErrorPanic(err, "❌ Fail to load caddy config")
+		if !live {
+			fmt.Printf("🔹 %s: blue is live, discarding green\n", service)
+			continue
+		}
+
+		srv, err := deployment.GetService(service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to get service: %s", service))
+		blue := stackService(stack, service)
+		fmt.Printf("🔹 Rolling out %s to %s\n", srv.Image, blue)
+		started := time.Now()
+		err = updateComposeService(stack, deployment, service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to roll out green to service: %s", service))
+		checkErrorPanic(waitForService(blue, started, args.Timeout), fmt.Sprintf("❌ %s did not converge, routing left on green", blue))
+
+		serverFile, _, err := caddyUpdateConfigDial(args.Env, stack, greenService(service), service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
+		routed = true
+	}
+	if routed {
+		applyRouting(args.Env, stack, args.Verbose)
+		fmt.Printf("✅ Routing updated\n")
+	}
+
+	for _, service := range args.Services {
+		green := stackService(stack, greenService(service))
+		checkErrorPanic(dockerServiceRemove(green), fmt.Sprintf("❌ Fail to remove service: %s", green))
+		fmt.Printf("✅ %s removed\n", green)
+	}
+}
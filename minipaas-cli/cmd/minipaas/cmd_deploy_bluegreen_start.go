@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type DeployBluegreenStartArgs struct {
+	BaseArgs
+	Services []string      `arg:"positional,required" help:"Routed services to deploy blue/green."`
+	Timeout  time.Duration `arg:"--timeout" help:"How long to wait for the green service to be healthy" default:"10m"`
+}
+
+func (args *DeployBluegreenStartArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	composeFiles := deployComposeFiles(args.Env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", composeFiles))
+
+	stack := cfg.StackName()
+
+	// Bring every green color up before switching any route, so all
+	// services change version at the same time.
+	for _, service := range args.Services {
+		srv, err := deployment.GetService(service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to get service: %s", service))
+
+		routes, err := caddyRoutesTo(args.Env, stack, service)
+		checkErrorPanic(err, "❌ Fail to load caddy config")
+		if routes == 0 {
+			log.Fatalf("❌ No route proxies to %s, expose it with `code route` first", service)
+		}
+
+		green := stackService(stack, greenService(service))
+		if _, err = dockerServiceInspect(green); err == nil {
+			log.Fatalf("❌ %s is already running, run `deploy bluegreen finish` first", green)
+		}
+
+		blue := stackService(stack, service)
+		live, err := dockerServiceInspect(blue)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to inspect service: %s", blue))
+		if !hasHealthcheck(live) {
+			log.Printf("⚠️ %s has no healthcheck, only waiting for its tasks to start", blue)
+		}
+
+		raw, err := composeSpec(live.RawSpec, deployment, srv)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to apply compose settings to service: %s", service))
+		spec, err := greenSpec(raw, live.Spec.Name, srv.Image)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to build green spec for service: %s", service))
+		started := time.Now()
+		checkErrorPanic(dockerServiceCreate(spec), fmt.Sprintf("❌ Fail to create service: %s", green))
+		fmt.Printf("✅ %s: %s\n", green, srv.Image)
+
+		if err = waitForService(green, started, args.Timeout); err != nil {
+			if rmErr := dockerServiceRemove(green); rmErr != nil {
+				log.Printf("⚠️ Fail to remove %s: %v", green, rmErr)
+			}
+			checkErrorPanic(err, fmt.Sprintf("❌ %s did not become healthy, routing left on blue", green))
+		}
+	}
+
+	for _, service := range args.Services {
+		serverFile, _, err := caddyUpdateConfigDial(args.Env, stack, service, greenService(service))
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
+	}
+	applyRouting(args.Env, stack, args.Verbose)
+	fmt.Printf("✅ Routing switched to green, run `deploy bluegreen switch` to go back or `deploy bluegreen finish` to complete\n")
+}
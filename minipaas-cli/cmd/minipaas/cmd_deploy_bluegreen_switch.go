@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+)
+
+type DeployBluegreenSwitchArgs struct {
+	BaseArgs
+	Services []string `arg:"positional,required" help:"Services whose routing is switched to the other color."`
+}
+
+func (args *DeployBluegreenSwitchArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	stack := cfg.StackName()
+	for _, service := range args.Services {
+		green := stackService(stack, greenService(service))
+		_, err = dockerServiceInspect(green)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to inspect service: %s", green))
+
+		live, err := greenIsLive(args.Env, stack, service)
+		checkErrorPanic(err, "❌ Fail to load caddy config")
+
+		from, to, color := service, greenService(service), "green"
+		if live {
+			from, to, color = to, from, "blue"
+		}
+		serverFile, _, err := caddyUpdateConfigDial(args.Env, stack, from, to)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
+		fmt.Printf("✅ %s: routing switched to %s\n", service, color)
+	}
+	applyRouting(args.Env, stack, args.Verbose)
+	fmt.Printf("✅ Routing updated\n")
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"fmt"
-	"path/filepath"
+	"log"
+	"maps"
+	"os"
+	"slices"
 )
 
 type DeployBuildArgs struct {
 	BaseArgs
+	Push     bool `arg:"--push" help:"Push the images to the registry after a successful build" default:"false"`
+	Retries  int  `arg:"--retries" help:"Times to retry a failed push" default:"3"`
+	Parallel int  `arg:"--parallel" help:"Number of images to build at the same time" default:"1"`
+	Quiet    bool `arg:"--quiet" help:"Only print the output of failed builds" default:"false"`
 }
 
 func (args *DeployBuildArgs) Run() {
@@ -14,19 +21,71 @@ func (args *DeployBuildArgs) Run() {
 	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
 	setApiEnvVars(args.Env, cfg, args.Verbose)
 
-	project, err := composeLoadDeployProject(append(cfg.Project.Files, filepath.Join(args.Env, appsFile)))
+	project, err := composeLoadDeployProject(deployComposeFiles(args.Env, cfg), cfg.StackName())
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", cfg.Project.Files))
 
-	for name, svc := range project.Services {
-		if svc.Build == nil {
+	retags, err := envRegistryRetags(args.Env, cfg)
+	checkErrorPanic(err, "❌ Fail to compare images with the configured registry")
+	if len(retags) > 0 {
+		log.Printf("⚠️ %s still names images for another registry, using %s; run `code retag` before `deploy rollout`", appsFile, cfg.RegistryName())
+		applyRegistryRetags(project, retags, cfg.Deploy.Version)
+	}
+
+	vars, err := resolveEnvValues(args.Env, cfg.Env)
+	checkErrorPanic(err, "❌ Failed to resolve env in minipaas.yaml")
+	applyEnvBuildArgs(project, vars)
+
+	buildx := dockerBuildxAvailable()
+	if !buildx {
+		log.Printf("⚠️ docker buildx is not available, falling back to docker build")
+	}
+	targets, err := buildTargets(project, buildx, args.Push)
+	checkErrorPanic(err, "❌ Failed to prepare builds")
+
+	results := runBuilds(targets, args.Parallel, args.Quiet, args.Verbose, os.Stdout)
+
+	images := map[string]string{}
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		if r.Target.PushedByBuild {
+			fmt.Printf("✅ %s: multi-platform image pushed by buildx\n", r.Target.label())
 			continue
 		}
-		buildArgs := buildCommandFromService(svc)
-		err = runCommand(buildArgs, args.Verbose)
-		if err != nil {
-			fmt.Printf("❌ %s: %s\n", name, err.Error())
-		} else {
-			fmt.Printf("✅ %s: %s\n", name, cfg.Deploy.Version)
+		for _, svc := range r.Target.Services {
+			images[svc] = r.Target.Image
+		}
+	}
+
+	pushFailed := 0
+	if args.Push && len(images) > 0 {
+		pushFailed = pushAndReport(images, args.Retries, args.Verbose)
+	}
+
+	buildFailed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			buildFailed++
 		}
 	}
+	if buildFailed > 0 {
+		fmt.Println("❌ Failed builds:")
+		_, err = printBuildFailures(os.Stdout, results)
+		checkErrorPanic(err, "❌ Failed to print build summary")
+		checkErrorPanic(fmt.Errorf("%d of %d build(s) failed", buildFailed, len(results)), "❌ Failed to build images")
+	}
+	if pushFailed > 0 {
+		checkErrorPanic(fmt.Errorf("%d of %d image(s) could not be pushed", pushFailed, len(images)), "❌ Failed to push images")
+	}
+}
+
+// pushAndReport pushes the images, prints the digest summary and returns the
+// number of services whose push failed.
+func pushAndReport(images map[string]string, retries int, verbose bool) int {
+	results := pushServiceImages(images, slices.Sorted(maps.Keys(images)), retries, verbose)
+
+	fmt.Println("🔹 Pushed images:")
+	checkErrorPanic(printPushSummary(os.Stdout, results), "❌ Failed to print push summary")
+	return countPushFailures(results)
 }
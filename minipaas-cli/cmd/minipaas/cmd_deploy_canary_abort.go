@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+)
+
+type DeployCanaryAbortArgs struct {
+	BaseArgs
+	Services []string `arg:"positional,required" help:"Services whose canary is aborted."`
+}
+
+func (args *DeployCanaryAbortArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	removeCanaries(args.Env, cfg.StackName(), args.Services, args.Verbose)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type DeployCanaryPromoteArgs struct {
+	BaseArgs
+	Services []string      `arg:"positional,required" help:"Services whose canary is promoted."`
+	Timeout  time.Duration `arg:"--timeout" help:"How long to wait for each service to converge" default:"10m"`
+}
+
+func (args *DeployCanaryPromoteArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	composeFiles := deployComposeFiles(args.Env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", composeFiles))
+
+	// Roll the spec the canary runs out to every service first, so routing
+	// only stops sending traffic to the canaries once they are redundant.
+	stack := cfg.StackName()
+	for _, service := range args.Services {
+		canary := canaryServiceName(stack, service)
+		_, err := dockerServiceInspect(canary)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to inspect canary service: %s", canary))
+
+		srv, err := deployment.GetService(service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to get service: %s", service))
+
+		main := stackService(stack, service)
+		fmt.Printf("🔹 Rolling out %s to %s\n", srv.Image, main)
+		started := time.Now()
+		err = updateComposeService(stack, deployment, service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to roll out canary to service: %s", service))
+		checkErrorPanic(waitForService(main, started, args.Timeout), fmt.Sprintf("❌ %s did not converge, the canary is kept", main))
+	}
+
+	removeCanaries(args.Env, stack, args.Services, args.Verbose)
+}
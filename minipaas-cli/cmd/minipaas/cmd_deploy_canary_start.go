@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+type DeployCanaryStartArgs struct {
+	BaseArgs
+	Services []string `arg:"positional,required" help:"Services to release with canary."`
+	Replicas uint64   `arg:"--replicas" help:"Replicas of the canary service." default:"1"`
+	Weight   int      `arg:"--weight" help:"Percentage of the routed traffic sent to the canary (1-99)." default:"10"`
+}
+
+func (args *DeployCanaryStartArgs) Run() {
+	if args.Weight < 1 || args.Weight > 99 {
+		log.Fatalf("❌ --weight must be between 1 and 99, got %d", args.Weight)
+	}
+
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	composeFiles := deployComposeFiles(args.Env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", composeFiles))
+
+	stack := cfg.StackName()
+	routed := false
+	for _, service := range args.Services {
+		srv, err := deployment.GetService(service)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to get service: %s", service))
+
+		canary := canaryServiceName(stack, service)
+		if _, err = dockerServiceInspect(canary); err == nil {
+			log.Fatalf("❌ Canary %s is already running, promote or abort it first", canary)
+		}
+
+		live, err := dockerServiceInspect(stackService(stack, service))
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to inspect service: %s", stackService(stack, service)))
+
+		raw, err := composeSpec(live.RawSpec, deployment, srv)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to apply compose settings to service: %s", service))
+		spec, err := canarySpec(raw, live.Spec.Name, srv.Image, args.Replicas)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to build canary spec for service: %s", service))
+		checkErrorPanic(dockerServiceCreate(spec), fmt.Sprintf("❌ Fail to create canary service: %s", canary))
+		fmt.Printf("✅ %s: %s (%d replicas)\n", canary, srv.Image, args.Replicas)
+
+		serverFile, changed, err := caddyUpdateConfigCanary(args.Env, stack, service, args.Weight)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
+		if changed == 0 {
+			log.Printf("⚠️ No route proxies to %s, the canary receives no routed traffic", service)
+			continue
+		}
+		fmt.Printf("✅ %s: %d%% of %d route(s) sent to the canary\n", serverFile, args.Weight, changed)
+		routed = true
+	}
+
+	if routed {
+		applyRouting(args.Env, stack, args.Verbose)
+		fmt.Printf("✅ Routing updated\n")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type DeployDiffArgs struct {
+	BaseArgs
+}
+
+func (args *DeployDiffArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	composeFiles := deployComposeFiles(args.Env, cfg)
+	project, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", composeFiles))
+
+	live, err := dockerServiceList()
+	checkErrorPanic(err, "❌ Failed to list services")
+	networks, err := dockerNetworkNames()
+	checkErrorPanic(err, "❌ Failed to list networks")
+
+	changes, unchanged := diffStack(project, live, networks, cfg.StackName())
+	if len(changes) == 0 {
+		fmt.Printf("✅ No changes, %d services up to date with version %s\n", unchanged, cfg.Deploy.Version)
+		return
+	}
+
+	printServiceChanges(os.Stdout, changes)
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	fmt.Printf("\n🔹 %d to create, %d to change, %d orphaned, %d unchanged\n",
+		counts[serviceCreated], counts[serviceChanged], counts[serviceOrphaned], unchanged)
+}
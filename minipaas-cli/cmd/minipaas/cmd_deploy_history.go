@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type DeployHistoryArgs struct {
+	BaseArgs
+	Images bool `arg:"--images" help:"Also print the image of every service" default:"false"`
+}
+
+func (args *DeployHistoryArgs) Run() {
+	history, historyFn, err := loadDeployHistory(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading deployment history: %s", historyFn))
+
+	if len(history.Entries) == 0 {
+		fmt.Printf("🔹 No deployment recorded yet in %s\n", historyFn)
+		return
+	}
+	checkErrorPanic(printDeployHistory(os.Stdout, history, args.Images), "❌ Failed to print history")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+type DeployJobRunArgs struct {
+	BaseArgs
+	Services []string      `arg:"positional,required" help:"Job services to run, one after the other."`
+	Timeout  time.Duration `arg:"--timeout" help:"How long to wait for each job to finish" default:"30m"`
+}
+
+func (args *DeployJobRunArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	images, err := deploymentImages(args.Env, cfg)
+	checkErrorPanic(err, "❌ Fail to load project files")
+
+	for _, service := range args.Services {
+		image, ok := images[service]
+		if !ok {
+			log.Fatalf("❌ Service %s not found in project", service)
+		}
+		code, err := runJob(cfg.StackName(), service, image, args.Timeout, os.Stdout)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to run job: %s", service))
+		if code != 0 {
+			log.Printf("❌ Job %s failed with exit code %d", service, code)
+			exitCode = code
+			return
+		}
+		fmt.Printf("✅ Job %s completed\n", service)
+	}
+}
+
+// deploymentImages returns the image of every service at the configured
+// version.
+func deploymentImages(env string, cfg Config) (map[string]string, error) {
+	composeFiles := deployComposeFiles(env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	if err != nil {
+		return nil, err
+	}
+	images := make(map[string]string, len(deployment.Services))
+	for name, srv := range deployment.Services {
+		images[name] = srv.Image
+	}
+	return images, nil
+}
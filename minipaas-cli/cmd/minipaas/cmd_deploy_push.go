@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+type DeployPushArgs struct {
+	BaseArgs
+	Retries int `arg:"--retries" help:"Times to retry a failed push" default:"3"`
+}
+
+func (args *DeployPushArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	project, err := composeLoadDeployProject(deployComposeFiles(args.Env, cfg), cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", cfg.Project.Files))
+
+	retags, err := envRegistryRetags(args.Env, cfg)
+	checkErrorPanic(err, "❌ Fail to compare images with the configured registry")
+	if len(retags) > 0 {
+		log.Printf("⚠️ %s still names images for another registry, using %s; run `code retag` before `deploy rollout`", appsFile, cfg.RegistryName())
+		applyRegistryRetags(project, retags, cfg.Deploy.Version)
+	}
+
+	images := map[string]string{}
+	for name, svc := range project.Services {
+		if svc.Build != nil {
+			images[name] = svc.Image
+		}
+	}
+	if len(images) == 0 {
+		log.Printf("⚠️ No service is built by MiniPaaS, nothing to push.")
+		return
+	}
+	if failed := pushAndReport(images, args.Retries, args.Verbose); failed > 0 {
+		checkErrorPanic(fmt.Errorf("%d of %d image(s) could not be pushed", failed, len(images)), "❌ Failed to push images")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type DeployRollbackArgs struct {
+	BaseArgs
+	To      int           `arg:"--to" help:"History entry to roll back to (default: the one before the current deployment)"`
+	Wait    bool          `arg:"--wait" help:"Wait until every stack service converges; fail on rollback, pause or timeout" default:"false"`
+	Timeout time.Duration `arg:"--timeout" help:"How long --wait waits for the services to converge" default:"10m"`
+}
+
+func (args *DeployRollbackArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+
+	history, historyFn, err := loadDeployHistory(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading deployment history: %s", historyFn))
+	target, err := history.rollbackTarget(args.To)
+	checkErrorPanic(err, "❌ Cannot find a deployment to roll back to")
+
+	// Render the compose files with the version of the restored entry, then
+	// pin each service to the exact image it ran.
+	cfg.Deploy.Version = target.Version
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	project, err := composeLoadDeployProject(deployComposeFiles(args.Env, cfg), cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", cfg.Project.Files))
+
+	override, skipped, err := rollbackOverride(target, slices.Collect(maps.Keys(project.Services)))
+	checkErrorPanic(err, "❌ Failed to render rollback override")
+	if len(skipped) > 0 {
+		log.Printf("⚠️ Services no longer defined, not restored: %s", strings.Join(skipped, ", "))
+	}
+
+	f, err := os.CreateTemp("", "minipaas-rollback-*.yaml")
+	checkErrorPanic(err, "❌ Failed to create rollback override")
+	defer os.Remove(f.Name())
+	_, err = f.Write(override)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	checkErrorPanic(err, "❌ Failed to write rollback override")
+
+	fmt.Printf("🔹 Rolling back to #%d (version %s, deployed %s)\n", target.Number, target.Version, target.Time.Local().Format(time.DateTime))
+	started := time.Now()
+	err = stackDeploy(args.Env, cfg, []string{f.Name()}, args.Verbose)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error rolling back to #%d", target.Number))
+
+	if args.Wait {
+		err = waitForRollout(cfg.StackName(), started, args.Timeout)
+		checkErrorPanic(err, fmt.Sprintf("❌ Rollback to #%d failed", target.Number))
+	}
+
+	entry, historyFn, err := recordDeployment(args.Env, cfg.StackName(), target.Version, target.Number)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to record deployment in %s", historyFn))
+	fmt.Printf("✅ Rolled back to #%d: %s (#%d)\n", target.Number, target.Version, entry.Number)
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"fmt"
-	"path/filepath"
+	"log"
+	"os"
+	"time"
 )
 
 type DeployRolloutArgs struct {
 	BaseArgs
+	Wait          bool          `arg:"--wait" help:"Wait until every stack service converges; fail on rollback, pause or timeout" default:"false"`
+	Timeout       time.Duration `arg:"--timeout" help:"How long --wait waits for the services to converge" default:"10m"`
+	BeforeRollout []string      `arg:"--before-rollout,separate" help:"Job service to run at the new version first, after the pre_rollout hook; a failure aborts the rollout"`
+	JobTimeout    time.Duration `arg:"--job-timeout" help:"How long to wait for each job of the hooks" default:"30m"`
 }
 
 func (args *DeployRolloutArgs) Run() {
@@ -14,8 +20,49 @@ func (args *DeployRolloutArgs) Run() {
 	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
 	setApiEnvVars(args.Env, cfg, args.Verbose)
 
-	composeFiles := cfg.Project.Files
-	composeFiles = append(composeFiles, filepath.Join(args.Env, appsFile))
+	stack := cfg.StackName()
+	hooks := cfg.Deploy.Hooks
+	for _, job := range args.BeforeRollout {
+		hooks.PreRollout = append(hooks.PreRollout, HookStep{Job: job})
+	}
+
+	var images map[string]string
+	if len(hookJobs(hooks)) > 0 {
+		images, err = deploymentImages(args.Env, cfg)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", cfg.Project.Files))
+		for _, job := range hookJobs(hooks) {
+			if _, ok := images[job]; !ok {
+				log.Fatalf("❌ Hook job %s not found in project", job)
+			}
+		}
+	}
+	failed := func(err error, msg string) {
+		if err != nil {
+			runFailureHooks(stack, hooks.OnFailure, images, args.JobTimeout, err)
+		}
+		checkErrorPanic(err, msg)
+	}
+
+	err = runHooks("pre_rollout", stack, hooks.PreRollout, images, args.JobTimeout)
+	failed(err, fmt.Sprintf("❌ Rollout of version %s aborted", cfg.Deploy.Version))
+
+	// The post_rollout hook runs against the converged stack.
+	wait := args.Wait || len(hooks.PostRollout) > 0
+	err = rolloutWaves(args.Env, cfg, wait, args.Timeout, args.Verbose)
+	failed(err, fmt.Sprintf("❌ Rollout of version %s failed", cfg.Deploy.Version))
+
+	entry, historyFn, err := recordDeployment(args.Env, stack, cfg.Deploy.Version, 0)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to record deployment in %s", historyFn))
+
+	err = runHooks("post_rollout", stack, hooks.PostRollout, images, args.JobTimeout)
+	failed(err, fmt.Sprintf("❌ Version %s is deployed (#%d) but its post_rollout hook failed", cfg.Deploy.Version, entry.Number))
+	fmt.Printf("✅ Deployment successful: %s (#%d)\n", cfg.Deploy.Version, entry.Number)
+}
+
+// stackDeploy runs `docker stack deploy` with the env compose files, followed
+// by any extra override files.
+func stackDeploy(env string, cfg Config, extra []string, verbose bool) error {
+	composeFiles := append(deployComposeFiles(env, cfg), extra...)
 
 	var files []string
 	for _, fn := range composeFiles {
@@ -23,8 +70,20 @@ func (args *DeployRolloutArgs) Run() {
 	}
 
 	deployArgs := append([]string{"docker", "stack", "deploy"}, files...)
-	deployArgs = append(deployArgs, "minipaas")
-	err = runCommand(deployArgs, args.Verbose)
-	checkErrorPanic(err, fmt.Sprintf("❌ Error deploying version %s", cfg.Deploy.Version))
-	fmt.Printf("✅ Deployment successful: %s\n", cfg.Deploy.Version)
+	deployArgs = append(deployArgs, cfg.StackName())
+	return runCommand(deployArgs, verbose)
+}
+
+// waitForRollout waits for every service of the stack to converge after a
+// deploy made at since.
+func waitForRollout(stack string, since time.Time, timeout time.Duration) error {
+	if dryRun {
+		return nil
+	}
+	fmt.Printf("🔹 Waiting for services to converge (timeout %s)\n", timeout)
+	fetch := func() ([]stackServiceState, error) {
+		return dockerServiceStates(stackFilter(stack))
+	}
+	opts := waitOptions{Timeout: timeout, Interval: waitPollInterval, Stable: waitStablePeriod, Since: since}
+	return waitForStack(fetch, opts, os.Stdout)
 }
@@ -13,9 +13,28 @@ func (args *DeployRoutingArgs) Run() {
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load configuration file: %s", configFile))
 	setApiEnvVars(args.Env, cfg, args.Verbose)
 
-	serverFile, payload, err := caddyLoadServers(args.Env)
+	applyRouting(args.Env, cfg.StackName(), args.Verbose)
+	fmt.Printf("✅ Routing updated\n")
+}
+
+// applyRouting loads env/caddy.json into the running Caddy container, keeping
+// the routes it serves for the other stacks sharing it.
+func applyRouting(env, stack string, verbose bool) {
+	serverFile, payload, err := caddyLoadServers(env)
 	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load server JSON: %s", serverFile))
 
+	containerID, err := caddyContainerID(stack)
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to obtain container ID for `%s`", stackService(stack, caddyService)))
+
+	live, err := dockerContainerExecOutput(containerID, []string{
+		"/usr/bin/wget",
+		"-O", "-", "-q",
+		"http://127.0.0.1:2019/config/",
+	}, verbose)
+	checkErrorPanic(err, "❌ Fail to read the current Caddy config")
+	payload, err = mergeCaddyRoutes(payload, []byte(live), stack)
+	checkErrorPanic(err, "❌ Fail to merge the current Caddy config")
+
 	cmdArgs := []string{
 		"/usr/bin/wget",
 		"-O", "-", "-q",
@@ -24,10 +43,6 @@ func (args *DeployRoutingArgs) Run() {
 		"http://127.0.0.1:2019/load",
 	}
 
-	containerID, err := getContainerID(CaddyContainerName)
-	checkErrorPanic(err, fmt.Sprintf("❌ Fail to obtain container ID for `%s`", CaddyContainerName))
-
-	err = dockerContainerExec(containerID, cmdArgs, args.Verbose)
+	err = dockerContainerExec(containerID, cmdArgs, verbose)
 	checkErrorPanic(err, "❌ Fail to update server in Caddy")
-	fmt.Printf("✅ Routing updated\n")
 }
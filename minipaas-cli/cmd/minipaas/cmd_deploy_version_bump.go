@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+)
+
+type DeployVersionBumpArgs struct {
+	BaseArgs
+	Strategy string `arg:"--strategy" help:"How to compute the next version: git, semver or date" default:"semver"`
+	Part     string `arg:"--part" help:"Semver part to bump: major, minor or patch" default:"patch"`
+}
+
+func (args *DeployVersionBumpArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+
+	version, err := nextVersion(args.Env, args.Strategy, cfg.Deploy.Version, args.Part)
+	checkErrorPanic(err, "❌ Failed to compute the next version")
+
+	previous := cfg.Deploy.Version
+	cfg.Deploy.Version = version
+	configFile, err = saveConfig(args.Env, cfg)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write configuration file: %s", configFile))
+	fmt.Printf("✅ Version bumped: %s -> %s\n", previous, version)
+}
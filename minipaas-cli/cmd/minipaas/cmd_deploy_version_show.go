@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+type DeployVersionShowArgs struct {
+	BaseArgs
+}
+
+func (args *DeployVersionShowArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	project, err := composeLoadDeployProject(deployComposeFiles(args.Env, cfg), cfg.StackName())
+	checkErrorPanic(err, fmt.Sprintf("❌ Fail to load project files: %s", cfg.Project.Files))
+
+	var built []string
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		if project.Services[name].Build != nil {
+			built = append(built, name)
+		}
+	}
+
+	running, err := dockerServiceList()
+	checkErrorPanic(err, "❌ Failed to list services")
+
+	fmt.Printf("🔹 Configured version: %s\n", cfg.Deploy.Version)
+	versions := compareRunningVersions(cfg.Deploy.Version, built, running, cfg.StackName())
+	checkErrorPanic(printRunningVersions(os.Stdout, versions), "❌ Failed to print versions")
+}
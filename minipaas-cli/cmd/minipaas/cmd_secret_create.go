@@ -13,7 +13,12 @@ type SecretCreateArgs struct {
 	BaseArgs
 	Name string   `arg:"--name" help:"Name of the Docker secret to create. If not provided, a unique name is generated."`
 	File string   `arg:"positional" help:"Path to file to use for secret content. If omitted, reads from STDIN."`
-	For  []string `arg:"--for,separate" help:"Containers that use the secret"`
+	For  []string `arg:"--for,separate" help:"Containers that use the secret, as service or service:target"`
+
+	Target string `arg:"--target" help:"Path the secret is mounted at. Defaults to the secret name."`
+	UID    string `arg:"--uid" help:"UID owning the mounted file"`
+	GID    string `arg:"--gid" help:"GID owning the mounted file"`
+	Mode   string `arg:"--mode" help:"Octal permissions of the mounted file, e.g. 0400"`
 }
 
 func (args *SecretCreateArgs) Run() {
@@ -36,16 +41,20 @@ func (args *SecretCreateArgs) Run() {
 		baseName = args.Name
 	}
 
+	mode, err := parseFileMode(args.Mode)
+	checkErrorPanic(err, "❌ Failed to parse --mode")
+	mounts, err := parseServiceMounts(args.For, serviceMount{Target: args.Target, UID: args.UID, GID: args.GID, Mode: mode})
+	checkErrorPanic(err, "❌ Failed to parse --for")
+
 	if len(content) == 0 {
 		log.Printf("⚠️ Input is empty, skipping.")
 		return
 	}
 
 	env := args.Env
-	service := args.For
 	verbose := args.Verbose
 
-	secretName, err := secretCreate(baseName, content, verbose)
+	secretName, err := secretCreate(cfg.StackName(), baseName, content, verbose)
 	checkErrorPanic(err, fmt.Sprintf("❌ Error creating secret for input: %s", baseName))
 	fmt.Printf("✅ Secret created: %s\n", secretName)
 
@@ -53,25 +62,11 @@ func (args *SecretCreateArgs) Run() {
 	cfg, _, err = loadConfig(env)
 	checkErrorPanic(err, "❌ Failed to load MiniPaaS configuration")
 
-	// Determine compose files to scan and patch
-	orderedFiles := composeFilesForEnv(env, cfg)
-
-	// Group services by owning compose file
-	svcPerFile, missing := groupServicesByComposeFile(orderedFiles, service)
-	if len(missing) > 0 {
-		checkErrorPanic(fmt.Errorf("services not found: %v", missing), "❌ Failed to find services in compose files")
-	}
-
 	// Patch each compose file that owns at least one target service
-	for file, svcs := range svcPerFile {
-		project, _, lerr := loadComposeFile(file)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to load compose file: %s", file))
-
-		lerr = addComposeSecret(project, secretName, baseName, svcs)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to update compose: %s", file))
-
-		_, lerr = saveComposeFile(file, project)
-		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to update compose file: %s", file))
+	orderedFiles := composeFilesForEnv(env, cfg)
+	changedFiles, err := updateComposeFilesAddSecret(orderedFiles, secretName, baseName, mounts)
+	checkErrorPanic(err, "❌ Failed to update compose files")
+	for _, file := range changedFiles {
 		fmt.Printf("✅ Updated compose file with secret: %s\n", file)
 	}
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+type SecretDetachArgs struct {
+	BaseArgs
+	Name string   `arg:"--name,required" help:"Name of the secret to detach. A base name detaches all of its versions."`
+	From []string `arg:"--from,separate,required" help:"Services that should stop mounting the secret"`
+}
+
+func (args *SecretDetachArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	changedFiles, err := updateComposeFilesDetachSecret(orderedFiles, args.Name, args.From)
+	checkErrorPanic(err, "❌ Failed to detach secret from compose files")
+	if len(changedFiles) == 0 {
+		log.Printf("⚠️ %s is not mounted by %v, nothing to do.", args.Name, args.From)
+		return
+	}
+	for _, file := range changedFiles {
+		fmt.Printf("✅ Updated compose file: %s\n", file)
+	}
+}
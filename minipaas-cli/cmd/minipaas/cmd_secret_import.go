@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/dotenv"
+)
+
+type SecretImportArgs struct {
+	BaseArgs
+	From    string   `arg:"--from,required" help:"Dotenv file to import. Each key becomes a secret named after the lowercased key."`
+	For     []string `arg:"--for,separate" help:"Containers that use the secrets"`
+	FileEnv bool     `arg:"--file-env" help:"Also set KEY_FILE=/run/secrets/<key> on the services, following the _FILE convention" default:"false"`
+}
+
+func (args *SecretImportArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	mounts, err := parseServiceMounts(args.For, serviceMount{})
+	checkErrorPanic(err, "❌ Failed to parse --for")
+	for _, m := range mounts {
+		if m.Target != "" {
+			checkErrorPanic(fmt.Errorf("--for %s:%s sets a target, which would be shared by every imported key", m.Service, m.Target), "❌ Failed to parse --for")
+		}
+	}
+
+	values, err := dotenv.ReadFile(args.From, nil)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed reading file: %s", args.From))
+	if len(values) == 0 {
+		log.Printf("⚠️ No keys found in %s, skipping.", args.From)
+		return
+	}
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	fileVars := map[string]string{}
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if values[key] == "" {
+			log.Printf("⚠️ %s is empty, skipping.", key)
+			continue
+		}
+		baseName := strings.ToLower(key)
+
+		secretName, err := secretCreate(cfg.StackName(), baseName, []byte(values[key]), args.Verbose)
+		checkErrorPanic(err, fmt.Sprintf("❌ Error creating secret for input: %s", key))
+		fmt.Printf("✅ Secret created: %s\n", secretName)
+		fileVars[key+"_FILE"] = secretMountPath(baseName, secretName)
+
+		if len(args.For) == 0 {
+			continue
+		}
+		changedFiles, err := updateComposeFilesAddSecret(orderedFiles, secretName, baseName, mounts)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to update compose files for secret: %s", baseName))
+		for _, file := range changedFiles {
+			fmt.Printf("✅ Updated compose file with secret: %s\n", file)
+		}
+	}
+
+	if !args.FileEnv || len(args.For) == 0 || len(fileVars) == 0 {
+		return
+	}
+	changedFiles, err := updateComposeFilesAddEnvironment(orderedFiles, fileVars, mountServices(mounts))
+	checkErrorPanic(err, "❌ Failed to update compose files with environment")
+	for _, file := range changedFiles {
+		fmt.Printf("✅ Updated compose file with environment: %s\n", file)
+	}
+}
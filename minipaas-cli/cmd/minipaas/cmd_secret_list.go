@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type SecretListArgs struct {
+	BaseArgs
+}
+
+func (args *SecretListArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	referenced, err := composeFilesSecretMounts(orderedFiles)
+	checkErrorPanic(err, "❌ Failed to collect secret references from compose files")
+
+	inSwarm, err := dockerSecretList(nil)
+	checkErrorPanic(err, "❌ Failed to list secrets")
+
+	entries := buildObjectInventory(referenced, inSwarm)
+	err = printObjectInventory(os.Stdout, entries)
+	checkErrorPanic(err, "❌ Failed to print secrets")
+
+	if missing := countObjectStatus(entries, objectStatusMissing); missing > 0 {
+		fmt.Printf("⚠️ %d secret version(s) referenced in compose are missing in Swarm: deploy rollout will fail\n", missing)
+	}
+	if unreferenced := countObjectStatus(entries, objectStatusUnreferenced); unreferenced > 0 {
+		fmt.Printf("⚠️ %d secret version(s) in Swarm are not referenced: see secret prune\n", unreferenced)
+	}
+}
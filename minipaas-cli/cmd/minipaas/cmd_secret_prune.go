@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+)
+
+type SecretPruneArgs struct {
+	BaseArgs
+	Delete bool `arg:"--delete" help:"Remove orphan secrets from Swarm. Without it, orphans are only reported." default:"false"`
+}
+
+func (args *SecretPruneArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	referenced, err := composeFilesSecretNames(composeFilesForEnv(args.Env, cfg))
+	checkErrorPanic(err, "❌ Failed to collect secret references from compose files")
+
+	services, err := dockerServiceList()
+	checkErrorPanic(err, "❌ Failed to list Swarm services")
+	mounted := serviceMountedSecrets(services)
+
+	// Unlabelled versions predate the stack label; they belong to this stack
+	// when their logical name is one its compose files use.
+	existing, err := stackObjects(dockerSecretList, cfg.StackName(), referencedBaseName(referenced))
+	checkErrorPanic(err, "❌ Failed to list secrets")
+
+	orphans := findOrphanObjects(existing, referenced, mounted)
+	if len(orphans) == 0 {
+		fmt.Println("✅ No orphan secrets found")
+		return
+	}
+
+	for _, name := range orphans {
+		fmt.Printf("🔹 Orphan secret: %s\n", name)
+	}
+	if !args.Delete {
+		fmt.Printf("⚠️ %d orphan secret(s) found. Re-run with --delete to remove them.\n", len(orphans))
+		return
+	}
+
+	failed := 0
+	for _, name := range orphans {
+		if err = dockerSecretRemove(name, args.Verbose); err != nil {
+			fmt.Printf("❌ Failed to remove secret %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ Secret removed: %s\n", name)
+	}
+	if failed > 0 {
+		checkErrorPanic(fmt.Errorf("%d of %d secret(s) could not be removed", failed, len(orphans)), "❌ Failed to prune secrets")
+	}
+}
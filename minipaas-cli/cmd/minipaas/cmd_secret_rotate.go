@@ -0,0 +1,20 @@
+package main
+
+type SecretRotateArgs struct {
+	BaseArgs
+	Name      string `arg:"--name,required" help:"Name of the secret to rotate. Either the base name or one of its hashed versions."`
+	File      string `arg:"positional" help:"Path to file to use for the new secret content. If omitted, reads from STDIN."`
+	Rollout   bool   `arg:"--rollout" help:"Update only the services that mount the secret and wait for them to converge" default:"false"`
+	DeleteOld bool   `arg:"--delete-old" help:"Remove the previous versions from Swarm once the services converge. Requires --rollout." default:"false"`
+}
+
+func (args *SecretRotateArgs) Run() {
+	rotateObject(secretRotation, objectRotateOptions{
+		Env:       args.Env,
+		Name:      args.Name,
+		File:      args.File,
+		Rollout:   args.Rollout,
+		DeleteOld: args.DeleteOld,
+		Verbose:   args.Verbose,
+	})
+}
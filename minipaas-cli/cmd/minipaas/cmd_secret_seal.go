@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type SecretSealArgs struct {
+	BaseArgs
+	KeyFile string   `arg:"--key-file,env:MINIPAAS_SECRETS_KEY_FILE,required" help:"Key used to seal secrets.enc.yaml. Keep it outside the repository; created on first seal."`
+	Name    string   `arg:"--name" help:"Name of the secret to seal. Defaults to the file name."`
+	File    string   `arg:"positional" help:"Path to file to use for secret content. If omitted, reads from STDIN."`
+	For     []string `arg:"--for,separate" help:"Containers that use the secret. Keeps the current ones if omitted."`
+}
+
+func (args *SecretSealArgs) Run() {
+	store, storeFile, err := loadSecretsStore(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load secrets file: %s", storeFile))
+
+	// Only bootstrap a key for a brand-new store; otherwise a typo in the
+	// key path would silently fork the store.
+	key, created, err := secretsKey(args.KeyFile, len(store.Secrets) == 0)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load key file: %s", args.KeyFile))
+	if created {
+		fmt.Printf("🔹 Generated new key: %s (keep it outside the repository)\n", args.KeyFile)
+	}
+	_, err = unsealSecretsStore(key, store)
+	checkErrorPanic(err, fmt.Sprintf("❌ Key %s does not open secrets file: %s", args.KeyFile, storeFile))
+
+	var content []byte
+	name := args.Name
+	if args.File != "" {
+		if name == "" {
+			name = filepath.Base(args.File)
+		}
+		content, err = os.ReadFile(args.File)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed reading file: %s", args.File))
+	} else {
+		if name == "" {
+			checkErrorPanic(errors.New("when no file is provided, --name is mandatory"), "❌ Failed to get name")
+		}
+		content, err = io.ReadAll(os.Stdin)
+		checkErrorPanic(err, "❌ Failed reading from STDIN")
+	}
+
+	if len(content) == 0 {
+		log.Printf("⚠️ Input is empty, skipping.")
+		return
+	}
+	if objectBaseName(name) != name {
+		checkErrorPanic(fmt.Errorf("invalid secret name %q", name), "❌ Use the base name without hash suffix")
+	}
+
+	current, exists := store.Secrets[name]
+	value, err := sealSecretEntry(key, name, content, current, exists)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to seal secret: %s", name))
+
+	services := current.For
+	if len(args.For) > 0 {
+		services = args.For
+	}
+	store.Secrets[name] = SealedSecret{For: services, Value: value}
+
+	storeFile, err = saveSecretsStore(args.Env, store)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write secrets file: %s", storeFile))
+	fmt.Printf("✅ Secret sealed: %s\n", name)
+	fmt.Println("✅ ", storeFile)
+}
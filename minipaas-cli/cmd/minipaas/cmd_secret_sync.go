@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type SecretSyncArgs struct {
+	BaseArgs
+	KeyFile string `arg:"--key-file,env:MINIPAAS_SECRETS_KEY_FILE,required" help:"Key used to seal secrets.enc.yaml."`
+}
+
+func (args *SecretSyncArgs) Run() {
+	cfg, configFile, err := loadConfig(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(args.Env, cfg, args.Verbose)
+
+	store, storeFile, err := loadSecretsStore(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load secrets file: %s", storeFile))
+
+	key, _, err := secretsKey(args.KeyFile, false)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load key file: %s", args.KeyFile))
+
+	plain, err := unsealSecretsStore(key, store)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to unseal secrets file: %s", storeFile))
+
+	orderedFiles := composeFilesForEnv(args.Env, cfg)
+	for _, name := range slices.Sorted(maps.Keys(plain)) {
+		entry := plain[name]
+
+		secretName, err := secretCreate(cfg.StackName(), name, []byte(entry.Value), args.Verbose)
+		checkErrorPanic(err, fmt.Sprintf("❌ Error creating secret for input: %s", name))
+		fmt.Printf("✅ Secret created: %s\n", secretName)
+
+		if len(entry.For) == 0 {
+			continue
+		}
+		mounts, err := parseServiceMounts(entry.For, serviceMount{})
+		checkErrorPanic(err, fmt.Sprintf("❌ Invalid services for secret: %s", name))
+		changedFiles, err := updateComposeFilesAddSecret(orderedFiles, secretName, name, mounts)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed to update compose files for secret: %s", name))
+		for _, file := range changedFiles {
+			fmt.Printf("✅ Updated compose file with secret: %s\n", file)
+		}
+	}
+}
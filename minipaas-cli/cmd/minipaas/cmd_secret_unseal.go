@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type SecretUnsealArgs struct {
+	BaseArgs
+	KeyFile string `arg:"--key-file,env:MINIPAAS_SECRETS_KEY_FILE,required" help:"Key used to seal secrets.enc.yaml."`
+	Name    string `arg:"--name" help:"Print only the value of this secret."`
+	Edit    bool   `arg:"--edit" help:"Open the decrypted secrets in $EDITOR and seal them back on save." default:"false"`
+}
+
+func (args *SecretUnsealArgs) Run() {
+	store, storeFile, err := loadSecretsStore(args.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load secrets file: %s", storeFile))
+
+	key, _, err := secretsKey(args.KeyFile, false)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to load key file: %s", args.KeyFile))
+
+	plain, err := unsealSecretsStore(key, store)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to unseal secrets file: %s", storeFile))
+
+	if args.Name != "" {
+		entry, ok := plain[args.Name]
+		if !ok {
+			checkErrorPanic(fmt.Errorf("secret %s not found", args.Name), fmt.Sprintf("❌ Failed to unseal secret: %s", args.Name))
+		}
+		_, err = os.Stdout.WriteString(entry.Value)
+		checkErrorPanic(err, "❌ Failed to write secret")
+		return
+	}
+
+	data, err := yaml.Marshal(plain)
+	checkErrorPanic(err, "❌ Failed to render secrets")
+
+	if !args.Edit {
+		_, err = os.Stdout.Write(data)
+		checkErrorPanic(err, "❌ Failed to write secrets")
+		return
+	}
+
+	if dryRun {
+		fmt.Fprintf(dryRunOutput, "🔹 [dry-run] would open %d secret(s) in the editor and seal the changes into %s\n", len(plain), storeFile)
+		return
+	}
+
+	// CreateTemp uses 0600, so the plaintext is only readable by the owner
+	tmp, err := os.CreateTemp("", "minipaas-secrets-*.yaml")
+	checkErrorPanic(err, "❌ Failed to create temporary file")
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write file: %s", tmp.Name()))
+	checkErrorPanic(tmp.Close(), fmt.Sprintf("❌ Failed to write file: %s", tmp.Name()))
+
+	err = runEditor(tmp.Name())
+	checkErrorPanic(err, "❌ Editor exited with error, secrets left unchanged")
+
+	edited, err := os.ReadFile(tmp.Name())
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to read file: %s", tmp.Name()))
+
+	updated := map[string]PlainSecret{}
+	err = yaml.Unmarshal(edited, &updated)
+	checkErrorPanic(err, "❌ Failed to parse edited secrets, secrets left unchanged")
+
+	if reflect.DeepEqual(updated, plain) {
+		fmt.Println("🔹 No changes")
+		return
+	}
+
+	store, err = sealSecretsStore(key, store, updated)
+	checkErrorPanic(err, "❌ Failed to seal secrets")
+	storeFile, err = saveSecretsStore(args.Env, store)
+	checkErrorPanic(err, fmt.Sprintf("❌ Failed to write secrets file: %s", storeFile))
+	fmt.Println("✅ ", storeFile)
+}
+
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -9,6 +9,10 @@ import (
 )
 
 func runCommand(cmd []string, verbose bool) error {
+	if dryRun {
+		printDryRunCommand(cmd)
+		return nil
+	}
 	if verbose {
 		fmt.Printf("🔹 Running: %v\n", cmd)
 	}
@@ -22,6 +26,10 @@ func runCommand(cmd []string, verbose bool) error {
 }
 
 func runCommandWithInput(cmd []string, input []byte, verbose bool) error {
+	if dryRun {
+		printDryRunCommand(cmd)
+		return nil
+	}
 	if verbose {
 		fmt.Printf("🔹 Running: %v\n", cmd)
 	}
@@ -36,6 +44,10 @@ func runCommandWithInput(cmd []string, input []byte, verbose bool) error {
 }
 
 func runCommandOutput(cmd []string, verbose bool) (string, error) {
+	if dryRun {
+		printDryRunCommand(cmd)
+		return "", nil
+	}
 	if verbose {
 		fmt.Printf("🔹 Running: %v\n", cmd)
 	}
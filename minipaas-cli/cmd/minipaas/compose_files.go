@@ -11,7 +11,7 @@ func composeLoadProject(files []string) (*types.Project, error) {
 
 	options, err := cli.NewProjectOptions(
 		files,
-		cli.WithName("minipaas"),
+		cli.WithName(defaultStack),
 		cli.WithResolvedPaths(false),
 		cli.WithConsistency(false),
 		cli.WithoutEnvironmentResolution,
@@ -28,12 +28,15 @@ func composeLoadProject(files []string) (*types.Project, error) {
 	return project, err
 }
 
-func composeLoadDeployProject(files []string) (*types.Project, error) {
+// composeLoadDeployProject loads files as `docker stack deploy` would deploy
+// them to stack, which prefixes the names of the networks, volumes, secrets
+// and configs it creates.
+func composeLoadDeployProject(files []string, stack string) (*types.Project, error) {
 	ctx := context.Background()
 
 	options, err := cli.NewProjectOptions(
 		files,
-		cli.WithName("minipaas"),
+		cli.WithName(stack),
 		cli.WithResolvedPaths(false),
 		cli.WithConsistency(false),
 		cli.WithOsEnv,
@@ -34,7 +34,7 @@ func TestComposeLoadDeployProject_WithEnv(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	p, err := composeLoadDeployProject([]string{f})
+	p, err := composeLoadDeployProject([]string{f}, defaultStack)
 	if err != nil {
 		t.Fatalf("composeLoadDeployProject err: %v", err)
 	}
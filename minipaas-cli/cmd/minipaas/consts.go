@@ -1,6 +0,0 @@
-package main
-
-const (
-	DBContainerName    = "minipaas_db_client"
-	CaddyContainerName = "minipaas_caddy"
-)
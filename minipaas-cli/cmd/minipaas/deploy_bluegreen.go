@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+const (
+	// The stack service is the blue color; the green color runs next to it
+	// while a blue/green deployment is in progress.
+	greenSuffix = "_green"
+	// bluegreenLabel marks a green service with the name of the stack
+	// service it runs next to.
+	bluegreenLabel = "minipaas.bluegreen.of"
+)
+
+// greenService returns the name of the green color of service, without the
+// stack prefix, as used in Caddy dials.
+func greenService(service string) string {
+	return service + greenSuffix
+}
+
+// greenSpec derives the spec of the green color of a live service: same
+// spec and replicas, new image.
+func greenSpec(raw json.RawMessage, live, image string) (map[string]any, error) {
+	return siblingSpec(raw, live, live+greenSuffix, image, bluegreenLabel)
+}
+
+// hasHealthcheck reports whether a service defines a healthcheck, so that
+// its tasks only count as running once it passes.
+func hasHealthcheck(svc swarmService) bool {
+	hc := svc.Spec.TaskTemplate.ContainerSpec.Healthcheck
+	return hc != nil && len(hc.Test) > 0 && hc.Test[0] != "NONE"
+}
+
+// waitForService waits for every task of a single service, changed at since,
+// to be running. Swarm keeps tasks with a healthcheck in "starting" until it
+// passes.
+func waitForService(name string, since time.Time, timeout time.Duration) error {
+	if dryRun {
+		return nil
+	}
+	fmt.Printf("🔹 Waiting for %s to be healthy (timeout %s)\n", name, timeout)
+	fetch := func() ([]stackServiceState, error) {
+		states, err := dockerServiceStates(dockerapi.Filters{"name": {name}})
+		if err != nil {
+			return nil, err
+		}
+		// The name filter matches prefixes.
+		for _, s := range states {
+			if s.Service.Spec.Name == name {
+				return []stackServiceState{s}, nil
+			}
+		}
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+	opts := waitOptions{Timeout: timeout, Interval: waitPollInterval, Stable: waitStablePeriod, Since: since}
+	return waitForStack(fetch, opts, os.Stdout)
+}
+
+// greenIsLive reports whether the Caddy routes of service dial its green
+// color.
+func greenIsLive(env, stack, service string) (bool, error) {
+	count, err := caddyRoutesTo(env, stack, greenService(service))
+	return count > 0, err
+}
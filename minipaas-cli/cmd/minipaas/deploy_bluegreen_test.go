@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+func TestGreenSpec(t *testing.T) {
+	spec, err := greenSpec(json.RawMessage(liveServiceSpec), "minipaas_api", "registry:5000/api:1.1.0")
+	if err != nil {
+		t.Fatalf("greenSpec: %v", err)
+	}
+	data, _ := json.Marshal(spec)
+	var svc swarmService
+	if err := json.Unmarshal([]byte(`{"Spec":`+string(data)+`}`), &svc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if svc.Spec.Name != "minipaas_api_green" || svc.Spec.Labels[bluegreenLabel] != "minipaas_api" || !isSiblingService(svc) {
+		t.Fatalf("unexpected name or labels: %#v", svc.Spec)
+	}
+	if r := svc.Spec.Mode.Replicated; r == nil || r.Replicas == nil || *r.Replicas != 2 {
+		t.Fatalf("expected live replicas kept: %#v", svc.Spec.Mode)
+	}
+	if svc.Spec.TaskTemplate.ContainerSpec.Image != "registry:5000/api:1.1.0" {
+		t.Fatalf("unexpected image %q", svc.Spec.TaskTemplate.ContainerSpec.Image)
+	}
+}
+
+func TestHasHealthcheck(t *testing.T) {
+	var svc swarmService
+	if hasHealthcheck(svc) {
+		t.Fatalf("expected no healthcheck")
+	}
+	svc.Spec.TaskTemplate.ContainerSpec.Healthcheck = &dockerapi.HealthConfig{Test: []string{"NONE"}}
+	if hasHealthcheck(svc) {
+		t.Fatalf("expected disabled healthcheck")
+	}
+	svc.Spec.TaskTemplate.ContainerSpec.Healthcheck.Test = []string{"CMD-SHELL", "wget -qO- --spider http://127.0.0.1:8080"}
+	if !hasHealthcheck(svc) {
+		t.Fatalf("expected healthcheck")
+	}
+}
+
+func TestWaitForService_IgnoresPrefixMatches(t *testing.T) {
+	orig, origInterval, origStable := dockerServiceStates, waitPollInterval, waitStablePeriod
+	t.Cleanup(func() { dockerServiceStates, waitPollInterval, waitStablePeriod = orig, origInterval, origStable })
+	waitPollInterval, waitStablePeriod = time.Millisecond, 0
+
+	now := time.Now()
+	running := task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", now)
+	starting := task(dockerapi.TaskStateRunning, dockerapi.TaskStateStarting, "", now)
+	var filters dockerapi.Filters
+	dockerServiceStates = func(f dockerapi.Filters) ([]stackServiceState, error) {
+		filters = f
+		return []stackServiceState{
+			replicatedState("minipaas_api_green", 1, "", running),
+			replicatedState("minipaas_api_green2", 1, "", starting),
+		}, nil
+	}
+
+	if err := waitForService("minipaas_api_green", time.Now(), time.Second); err != nil {
+		t.Fatalf("waitForService: %v", err)
+	}
+	if filters["name"][0] != "minipaas_api_green" {
+		t.Fatalf("unexpected filters %v", filters)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	canarySuffix = "_canary"
+	// canaryLabel marks a canary service with the name of the service it
+	// shadows.
+	canaryLabel = "minipaas.canary.of"
+)
+
+func canaryServiceName(stack, service string) string {
+	return stackService(stack, service) + canarySuffix
+}
+
+// canarySpec derives the spec of the canary sibling of a live service with
+// its own image and replica count.
+func canarySpec(raw json.RawMessage, live, image string, replicas uint64) (map[string]any, error) {
+	spec, err := siblingSpec(raw, live, live+canarySuffix, image, canaryLabel)
+	if err != nil {
+		return nil, err
+	}
+	spec["Mode"] = map[string]any{"Replicated": map[string]any{"Replicas": replicas}}
+	return spec, nil
+}
+
+// removeCanaries restores the routes of services to their main upstream and
+// then removes their canary services.
+func removeCanaries(env, stack string, services []string, verbose bool) {
+	routed := false
+	for _, service := range services {
+		serverFile, changed, err := caddyUpdateConfigCanary(env, stack, service, 0)
+		checkErrorPanic(err, fmt.Sprintf("❌ Fail to update caddy config: %s", serverFile))
+		routed = routed || changed > 0
+	}
+	if routed {
+		applyRouting(env, stack, verbose)
+		fmt.Printf("✅ Routing restored\n")
+	}
+
+	for _, service := range services {
+		canary := canaryServiceName(stack, service)
+		checkErrorPanic(dockerServiceRemove(canary), fmt.Sprintf("❌ Fail to remove canary service: %s", canary))
+		fmt.Printf("✅ %s removed\n", canary)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const liveServiceSpec = `{
+  "Name": "minipaas_api",
+  "Labels": {"com.docker.stack.namespace": "minipaas"},
+  "TaskTemplate": {
+    "ContainerSpec": {
+      "Image": "registry:5000/api:1.0.0@sha256:abc",
+      "Labels": {"com.docker.stack.namespace": "minipaas", "app": "api"},
+      "Secrets": [{"SecretID": "s1", "SecretName": "db.267da420"}]
+    },
+    "Networks": [{"Target": "net1", "Aliases": ["api"]}]
+  },
+  "Mode": {"Replicated": {"Replicas": 2}},
+  "EndpointSpec": {"Mode": "vip", "Ports": [{"TargetPort": 8080, "PublishedPort": 8080}]}
+}`
+
+func TestCanarySpec(t *testing.T) {
+	spec, err := canarySpec(json.RawMessage(liveServiceSpec), "minipaas_api", "registry:5000/api:1.1.0", 1)
+	if err != nil {
+		t.Fatalf("canarySpec: %v", err)
+	}
+
+	data, _ := json.Marshal(spec)
+	var svc swarmService
+	if err := json.Unmarshal([]byte(`{"Spec":`+string(data)+`}`), &svc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if svc.Spec.Name != "minipaas_api_canary" || svc.Spec.Labels[canaryLabel] != "minipaas_api" ||
+		svc.Spec.Labels["com.docker.stack.namespace"] != "" {
+		t.Fatalf("unexpected name or labels: %#v", svc.Spec)
+	}
+	container := svc.Spec.TaskTemplate.ContainerSpec
+	if container.Image != "registry:5000/api:1.1.0" || len(container.Secrets) != 1 ||
+		container.Labels["com.docker.stack.namespace"] != "" || container.Labels["app"] != "api" {
+		t.Fatalf("unexpected container spec: %#v", svc.Spec.TaskTemplate.ContainerSpec)
+	}
+	if r := svc.Spec.Mode.Replicated; r == nil || r.Replicas == nil || *r.Replicas != 1 {
+		t.Fatalf("unexpected mode: %#v", svc.Spec.Mode)
+	}
+
+	var raw struct {
+		TaskTemplate struct {
+			Networks []map[string]any
+		}
+		EndpointSpec map[string]any
+	}
+	_ = json.Unmarshal(data, &raw)
+	if _, ok := raw.TaskTemplate.Networks[0]["Aliases"]; ok || raw.TaskTemplate.Networks[0]["Target"] != "net1" {
+		t.Fatalf("expected network kept without aliases: %#v", raw.TaskTemplate.Networks)
+	}
+	if _, ok := raw.EndpointSpec["Ports"]; ok || raw.EndpointSpec["Mode"] != "vip" {
+		t.Fatalf("expected published ports dropped: %#v", raw.EndpointSpec)
+	}
+
+	if _, err = canarySpec(json.RawMessage(`{"Name": "minipaas_api"}`), "minipaas_api", "img", 1); err == nil {
+		t.Fatalf("expected error without task template")
+	}
+}
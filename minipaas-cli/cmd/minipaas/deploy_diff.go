@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// Make external calls overridable for tests
+var dockerNetworkNames = func() (map[string]string, error) {
+	names := map[string]string{}
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		networks, err := c.NetworkList(ctx, nil)
+		for _, n := range networks {
+			names[n.ID] = n.Name
+		}
+		return err
+	})
+	return names, err
+}
+
+// diffFields are the service settings compared by deploy diff, in display
+// order. Scalar fields hold a single value.
+var diffFields = []string{"image", "replicas", "env", "secrets", "configs", "labels", "container labels", "resources", "networks"}
+
+var scalarDiffFields = map[string]bool{"image": true, "replicas": true}
+
+// serviceView is the deploy-relevant state of a service, built either from
+// the compose project or from the live spec so both compare field by field.
+type serviceView map[string][]string
+
+type fieldChange struct {
+	Field   string
+	Removed []string
+	Added   []string
+}
+
+const (
+	serviceCreated  = "created"
+	serviceChanged  = "changed"
+	serviceOrphaned = "orphaned"
+)
+
+type serviceChange struct {
+	Service string
+	Kind    string
+	Fields  []fieldChange
+	View    serviceView
+}
+
+// composeServiceView renders a service of the merged deploy project the way
+// `docker stack deploy` would create it.
+func composeServiceView(project *types.Project, svc types.ServiceConfig) serviceView {
+	view := serviceView{"image": {svc.Image}}
+
+	replicas := "1"
+	if d := svc.Deploy; d != nil {
+		switch d.Mode {
+		case "", "replicated":
+			if d.Replicas != nil {
+				replicas = strconv.Itoa(*d.Replicas)
+			}
+		default:
+			replicas = d.Mode
+		}
+	}
+	view["replicas"] = []string{replicas}
+
+	for _, k := range slices.Sorted(maps.Keys(svc.Environment)) {
+		if v := svc.Environment[k]; v != nil {
+			view["env"] = append(view["env"], k+"="+*v)
+		} else {
+			view["env"] = append(view["env"], k)
+		}
+	}
+
+	for _, s := range svc.Secrets {
+		name := s.Source
+		if decl, ok := project.Secrets[s.Source]; ok && decl.Name != "" {
+			name = decl.Name
+		}
+		view["secrets"] = append(view["secrets"], name+" -> "+secretMountPath(s.Target, s.Source))
+	}
+	for _, c := range svc.Configs {
+		name := c.Source
+		if decl, ok := project.Configs[c.Source]; ok && decl.Name != "" {
+			name = decl.Name
+		}
+		view["configs"] = append(view["configs"], name+" -> "+configMountPath(c.Target, c.Source))
+	}
+
+	view["container labels"] = labelValues(svc.Labels)
+	if d := svc.Deploy; d != nil {
+		view["labels"] = labelValues(d.Labels)
+		if l := d.Resources.Limits; l != nil {
+			view["resources"] = append(view["resources"], resourceValues("limits", cpusToNano(l.NanoCPUs), int64(l.MemoryBytes), l.Pids)...)
+		}
+		if r := d.Resources.Reservations; r != nil {
+			view["resources"] = append(view["resources"], resourceValues("reservations", cpusToNano(r.NanoCPUs), int64(r.MemoryBytes), 0)...)
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(svc.Networks)) {
+		name := key
+		if decl, ok := project.Networks[key]; ok && decl.Name != "" {
+			name = decl.Name
+		}
+		view["networks"] = append(view["networks"], name)
+	}
+
+	for field, values := range view {
+		slices.Sort(values)
+		view[field] = values
+	}
+	return view
+}
+
+// liveServiceView renders the spec of a running service. networks maps
+// network IDs to names.
+func liveServiceView(svc swarmService, networks map[string]string) serviceView {
+	spec := svc.Spec
+	container := spec.TaskTemplate.ContainerSpec
+
+	// Swarm pins the digest of the image it resolved; compose does not.
+	image, _, _ := strings.Cut(container.Image, "@")
+	view := serviceView{"image": {image}}
+
+	replicas := "1"
+	switch m := spec.Mode; {
+	case m.Global != nil:
+		replicas = "global"
+	case m.ReplicatedJob != nil:
+		replicas = "replicated-job"
+	case m.GlobalJob != nil:
+		replicas = "global-job"
+	case m.Replicated != nil && m.Replicated.Replicas != nil:
+		replicas = strconv.FormatUint(*m.Replicated.Replicas, 10)
+	}
+	view["replicas"] = []string{replicas}
+
+	view["env"] = slices.Clone(container.Env)
+	for _, s := range container.Secrets {
+		target := ""
+		if s.File != nil {
+			target = s.File.Name
+		}
+		view["secrets"] = append(view["secrets"], s.SecretName+" -> "+secretMountPath(target, s.SecretName))
+	}
+	for _, c := range container.Configs {
+		target := ""
+		if c.File != nil {
+			target = c.File.Name
+		}
+		view["configs"] = append(view["configs"], c.ConfigName+" -> "+configMountPath(target, c.ConfigName))
+	}
+
+	view["labels"] = labelValues(spec.Labels)
+	view["container labels"] = labelValues(container.Labels)
+
+	if res := spec.TaskTemplate.Resources; res != nil {
+		if l := res.Limits; l != nil {
+			view["resources"] = append(view["resources"], resourceValues("limits", l.NanoCPUs, l.MemoryBytes, l.Pids)...)
+		}
+		if r := res.Reservations; r != nil {
+			view["resources"] = append(view["resources"], resourceValues("reservations", r.NanoCPUs, r.MemoryBytes, 0)...)
+		}
+	}
+
+	for _, n := range spec.TaskTemplate.Networks {
+		name, ok := networks[n.Target]
+		if !ok {
+			name = n.Target
+		}
+		view["networks"] = append(view["networks"], name)
+	}
+
+	for field, values := range view {
+		slices.Sort(values)
+		view[field] = values
+	}
+	return view
+}
+
+// labelValues renders labels as sorted key=value pairs, leaving out the
+// labels `docker stack deploy` adds itself.
+func labelValues(labels map[string]string) []string {
+	var values []string
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if strings.HasPrefix(k, "com.docker.stack.") {
+			continue
+		}
+		values = append(values, k+"="+labels[k])
+	}
+	return values
+}
+
+func cpusToNano(cpus types.NanoCPUs) int64 {
+	return int64(float64(cpus.Value())*1e9 + 0.5)
+}
+
+func resourceValues(kind string, nanoCPUs, memory, pids int64) []string {
+	var values []string
+	if nanoCPUs != 0 {
+		// float32 precision matches what compose parses.
+		cpus := float32(float64(nanoCPUs) / 1e9)
+		values = append(values, kind+".cpus="+strconv.FormatFloat(float64(cpus), 'f', -1, 32))
+	}
+	if memory != 0 {
+		values = append(values, kind+".memory="+formatBytes(memory))
+	}
+	if pids != 0 {
+		values = append(values, kind+".pids="+strconv.FormatInt(pids, 10))
+	}
+	return values
+}
+
+// formatBytes renders a byte count with the largest binary unit dividing it.
+func formatBytes(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+// diffViews returns the fields that differ between the live and the wanted
+// view of a service.
+func diffViews(live, wanted serviceView) []fieldChange {
+	var changes []fieldChange
+	for _, field := range diffFields {
+		removed, added := diffValues(live[field], wanted[field])
+		if len(removed) > 0 || len(added) > 0 {
+			changes = append(changes, fieldChange{Field: field, Removed: removed, Added: added})
+		}
+	}
+	return changes
+}
+
+// diffValues returns the values only in before and only in after. Both are
+// sorted.
+func diffValues(before, after []string) (removed, added []string) {
+	for _, v := range before {
+		if !slices.Contains(after, v) {
+			removed = append(removed, v)
+		}
+	}
+	for _, v := range after {
+		if !slices.Contains(before, v) {
+			added = append(added, v)
+		}
+	}
+	return removed, added
+}
+
+// diffStack compares the deploy project with the live services of stack and
+// returns the services that would be created or changed, and those running
+// but no longer defined. It also returns the number of unchanged services.
+func diffStack(project *types.Project, live []swarmService, networks map[string]string, stack string) ([]serviceChange, int) {
+	running := map[string]swarmService{}
+	for _, svc := range live {
+		if strings.HasPrefix(svc.Spec.Name, stack+"_") && !isSiblingService(svc) {
+			running[svc.Spec.Name] = svc
+		}
+	}
+
+	var changes []serviceChange
+	unchanged := 0
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		full := stackService(stack, name)
+		wanted := composeServiceView(project, project.Services[name])
+		svc, ok := running[full]
+		if !ok {
+			changes = append(changes, serviceChange{Service: full, Kind: serviceCreated, View: wanted})
+			continue
+		}
+		delete(running, full)
+		if fields := diffViews(liveServiceView(svc, networks), wanted); len(fields) > 0 {
+			changes = append(changes, serviceChange{Service: full, Kind: serviceChanged, Fields: fields})
+		} else {
+			unchanged++
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(running)) {
+		changes = append(changes, serviceChange{Service: name, Kind: serviceOrphaned})
+	}
+	return changes, unchanged
+}
+
+func printServiceChanges(w io.Writer, changes []serviceChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case serviceCreated:
+			fmt.Fprintf(w, "+ %s (new service)\n", c.Service)
+			for _, field := range diffFields {
+				if values := c.View[field]; len(values) > 0 {
+					printFieldChange(w, fieldChange{Field: field, Added: values})
+				}
+			}
+		case serviceOrphaned:
+			fmt.Fprintf(w, "- %s (orphaned: running but not defined, not removed by rollout)\n", c.Service)
+		default:
+			fmt.Fprintf(w, "~ %s\n", c.Service)
+			for _, f := range c.Fields {
+				printFieldChange(w, f)
+			}
+		}
+	}
+}
+
+func printFieldChange(w io.Writer, f fieldChange) {
+	if scalarDiffFields[f.Field] {
+		after := "(none)"
+		if len(f.Added) > 0 {
+			after = f.Added[0]
+		}
+		if len(f.Removed) == 0 {
+			fmt.Fprintf(w, "    %s: %s\n", f.Field, after)
+		} else {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", f.Field, f.Removed[0], after)
+		}
+		return
+	}
+	fmt.Fprintf(w, "    %s:\n", f.Field)
+	for _, v := range f.Removed {
+		fmt.Fprintf(w, "      - %s\n", v)
+	}
+	for _, v := range f.Added {
+		fmt.Fprintf(w, "      + %s\n", v)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffComposeFixture = `services:
+  api:
+    image: registry:5000/api:1.1.0
+    environment:
+      LOG_LEVEL: info
+      PORT: "8080"
+    secrets:
+      - source: db.267da420
+        target: db
+    configs:
+      - source: app.conf.0123abcd
+        target: /etc/app.conf
+    labels:
+      team: core
+    deploy:
+      replicas: 3
+      labels:
+        caddy: api
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 256M
+  worker:
+    image: registry:5000/worker:1.1.0
+secrets:
+  db.267da420:
+    external: true
+configs:
+  app.conf.0123abcd:
+    external: true
+`
+
+const diffLiveFixture = `[
+  {
+    "ID": "svc1",
+    "Spec": {
+      "Name": "minipaas_api",
+      "Labels": {"com.docker.stack.namespace": "minipaas", "caddy": "api"},
+      "Mode": {"Replicated": {"Replicas": 2}},
+      "TaskTemplate": {
+        "ContainerSpec": {
+          "Image": "registry:5000/api:1.0.0@sha256:abc",
+          "Labels": {"com.docker.stack.namespace": "minipaas", "team": "core"},
+          "Env": ["PORT=8080", "LOG_LEVEL=debug"],
+          "Secrets": [{"File": {"Name": "db"}, "SecretID": "s1", "SecretName": "db.267da420"}],
+          "Configs": [{"File": {"Name": "/etc/app.conf"}, "ConfigID": "c1", "ConfigName": "app.conf.0123abcd"}]
+        },
+        "Resources": {"Limits": {"NanoCPUs": 500000000, "MemoryBytes": 268435456}},
+        "Networks": [{"Target": "n1", "Aliases": ["api"]}]
+      }
+    }
+  },
+  {
+    "ID": "svc2",
+    "Spec": {
+      "Name": "minipaas_legacy",
+      "Mode": {"Replicated": {"Replicas": 1}},
+      "TaskTemplate": {"ContainerSpec": {"Image": "registry:5000/legacy:0.1"}}
+    }
+  },
+  {
+    "ID": "svc3",
+    "Spec": {
+      "Name": "minipaas_api_canary",
+      "Labels": {"minipaas.canary.of": "minipaas_api"},
+      "TaskTemplate": {"ContainerSpec": {"Image": "registry:5000/api:1.1.0"}}
+    }
+  }
+]`
+
+func TestDiffStack(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "compose.yaml")
+	if err := os.WriteFile(fn, []byte(diffComposeFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := composeLoadDeployProject([]string{fn}, defaultStack)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	live, err := parseServiceInspect([]byte(diffLiveFixture))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	changes, unchanged := diffStack(project, live, map[string]string{"n1": "minipaas_default"}, "minipaas")
+	if unchanged != 0 || len(changes) != 3 {
+		t.Fatalf("unexpected changes %+v, unchanged %d", changes, unchanged)
+	}
+
+	api := changes[0]
+	if api.Service != "minipaas_api" || api.Kind != serviceChanged {
+		t.Fatalf("unexpected first change %+v", api)
+	}
+	want := []fieldChange{
+		{Field: "image", Removed: []string{"registry:5000/api:1.0.0"}, Added: []string{"registry:5000/api:1.1.0"}},
+		{Field: "replicas", Removed: []string{"2"}, Added: []string{"3"}},
+		{Field: "env", Removed: []string{"LOG_LEVEL=debug"}, Added: []string{"LOG_LEVEL=info"}},
+	}
+	if !reflect.DeepEqual(api.Fields, want) {
+		t.Fatalf("api fields =\n%+v\nwant\n%+v", api.Fields, want)
+	}
+
+	if changes[1].Service != "minipaas_worker" || changes[1].Kind != serviceCreated {
+		t.Fatalf("expected worker to be created, got %+v", changes[1])
+	}
+	if changes[2].Service != "minipaas_legacy" || changes[2].Kind != serviceOrphaned {
+		t.Fatalf("expected legacy to be orphaned, got %+v", changes[2])
+	}
+
+	var out bytes.Buffer
+	printServiceChanges(&out, changes)
+	for _, line := range []string{
+		"~ minipaas_api",
+		"    image: registry:5000/api:1.0.0 -> registry:5000/api:1.1.0",
+		"      - LOG_LEVEL=debug",
+		"      + LOG_LEVEL=info",
+		"+ minipaas_worker (new service)",
+		"    networks:\n      + minipaas_default",
+		"- minipaas_legacy (orphaned",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("output missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestResourceValues(t *testing.T) {
+	got := resourceValues("limits", cpusToNano(0.1), 1536<<20, 100)
+	want := []string{"limits.cpus=0.1", "limits.memory=1536MiB", "limits.pids=100"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resourceValues = %v, want %v", got, want)
+	}
+	if got := resourceValues("limits", 100000000, 1000, 0); !reflect.DeepEqual(got, []string{"limits.cpus=0.1", "limits.memory=1000B"}) {
+		t.Fatalf("resourceValues = %v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Make external calls overridable for tests
+var currentOperator = func() string {
+	if op := os.Getenv("MINIPAAS_OPERATOR"); op != "" {
+		return op
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// stackImages maps each service of stack, without the stack prefix, to the
+// image reference it runs. Canary and blue/green services are not part of a
+// deployment.
+func stackImages(services []swarmService, stack string) map[string]string {
+	images := map[string]string{}
+	prefix := stack + "_"
+	for _, svc := range services {
+		name, ok := strings.CutPrefix(svc.Spec.Name, prefix)
+		if !ok || isSiblingService(svc) {
+			continue
+		}
+		images[name] = svc.Spec.TaskTemplate.ContainerSpec.Image
+	}
+	return images
+}
+
+// recordDeployment appends the current state of stack to the env history.
+// rollbackTo is the number of the restored entry, if any.
+func recordDeployment(env, stack, version string, rollbackTo int) (DeployEntry, string, error) {
+	history, fn, err := loadDeployHistory(env)
+	if err != nil {
+		return DeployEntry{}, fn, err
+	}
+	if dryRun {
+		// The live images are not the deployed ones, as nothing was deployed.
+		fmt.Fprintf(dryRunOutput, "🔹 [dry-run] would record the deployment in %s\n", fn)
+		return history.add(DeployEntry{Version: version, RollbackTo: rollbackTo}), fn, nil
+	}
+
+	services, err := dockerServiceList()
+	if err != nil {
+		return DeployEntry{}, fn, err
+	}
+
+	// Outside a git checkout the SHA is simply not recorded.
+	sha, _ := gitOutput(env, "rev-parse", "HEAD")
+
+	entry := history.add(DeployEntry{
+		Version:    version,
+		Time:       versionNow().UTC().Truncate(time.Second),
+		Operator:   currentOperator(),
+		GitSHA:     sha,
+		RollbackTo: rollbackTo,
+		Images:     stackImages(services, stack),
+	})
+	fn, err = saveDeployHistory(env, history)
+	return entry, fn, err
+}
+
+// rollbackOverride renders a compose override that pins every service of
+// entry still defined in services to its recorded image. Services no longer
+// defined are returned as skipped.
+func rollbackOverride(entry DeployEntry, services []string) ([]byte, []string, error) {
+	pinned := map[string]map[string]string{}
+	var skipped []string
+	for _, name := range slices.Sorted(maps.Keys(entry.Images)) {
+		if !slices.Contains(services, name) {
+			skipped = append(skipped, name)
+			continue
+		}
+		pinned[name] = map[string]string{"image": entry.Images[name]}
+	}
+	data, err := yaml.Marshal(map[string]any{"services": pinned})
+	return data, skipped, err
+}
+
+func printDeployHistory(w io.Writer, history DeployHistory, images bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tVERSION\tDEPLOYED\tOPERATOR\tGIT\tNOTE")
+	for _, e := range slices.Backward(history.Entries) {
+		sha := e.GitSHA
+		if len(sha) > 12 {
+			sha = sha[:12]
+		}
+		note := ""
+		if e.RollbackTo != 0 {
+			note = fmt.Sprintf("rollback to #%d", e.RollbackTo)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", e.Number, e.Version, e.Time.Local().Format(time.DateTime), e.Operator, sha, note)
+	}
+	if err := tw.Flush(); err != nil || !images {
+		return err
+	}
+
+	for _, e := range slices.Backward(history.Entries) {
+		fmt.Fprintf(w, "\n🔹 #%d images:\n", e.Number)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range slices.Sorted(maps.Keys(e.Images)) {
+			fmt.Fprintf(tw, "   %s\t%s\n", name, e.Images[name])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStackImages(t *testing.T) {
+	services, err := parseServiceInspect([]byte(serviceInspectFixture))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	services = append(services, swarmService{})
+	services[2].Spec.Name = "other_api"
+
+	want := map[string]string{
+		"api":    "registry:5000/api:1.0.0@sha256:abc",
+		"worker": "registry:5000/api:1.0.0",
+	}
+	if got := stackImages(services, "minipaas"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("stackImages = %v, want %v", got, want)
+	}
+}
+
+func TestRecordDeployment(t *testing.T) {
+	origList, origGit, origNow, origOp := dockerServiceList, gitOutput, versionNow, currentOperator
+	t.Cleanup(func() {
+		dockerServiceList, gitOutput, versionNow, currentOperator = origList, origGit, origNow, origOp
+	})
+
+	dockerServiceList = func() ([]swarmService, error) {
+		return parseServiceInspect([]byte(serviceInspectFixture))
+	}
+	gitOutput = func(dir string, args ...string) (string, error) { return "0123456789abcdef", nil }
+	versionNow = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC) }
+	currentOperator = func() string { return "ci" }
+
+	env := t.TempDir()
+	if _, _, err := recordDeployment(env, "minipaas", "1.0.0", 0); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	entry, _, err := recordDeployment(env, "minipaas", "0.9.0", 1)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if entry.Number != 2 || entry.RollbackTo != 1 {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	history, _, err := loadDeployHistory(env)
+	if err != nil || len(history.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v, %v", history, err)
+	}
+	first := history.Entries[0]
+	if first.Operator != "ci" || first.GitSHA != "0123456789abcdef" || first.Images["api"] != "registry:5000/api:1.0.0@sha256:abc" {
+		t.Fatalf("unexpected entry %+v", first)
+	}
+
+	var out bytes.Buffer
+	if err := printDeployHistory(&out, history, true); err != nil {
+		t.Fatalf("print: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "2 ") || !strings.Contains(lines[1], "rollback to #1") || !strings.Contains(out.String(), "api     registry:5000/api:1.0.0@sha256:abc") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRollbackOverride(t *testing.T) {
+	entry := DeployEntry{Images: map[string]string{
+		"api":    "registry:5000/api:1.0.0@sha256:abc",
+		"legacy": "registry:5000/legacy:0.1",
+	}}
+
+	data, skipped, err := rollbackOverride(entry, []string{"api", "worker"})
+	if err != nil {
+		t.Fatalf("override: %v", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"legacy"}) {
+		t.Fatalf("skipped = %v", skipped)
+	}
+	want := "services:\n  api:\n    image: registry:5000/api:1.0.0@sha256:abc\n"
+	if string(data) != want {
+		t.Fatalf("override =\n%s\nwant\n%s", data, want)
+	}
+}
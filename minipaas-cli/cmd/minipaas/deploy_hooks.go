@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// rolloutErrorEnv carries the error of a failed rollout to the commands of
+// the on_failure hook.
+const rolloutErrorEnv = "MINIPAAS_ROLLOUT_ERROR"
+
+func (s HookStep) String() string {
+	if s.Job != "" {
+		return "job " + s.Job
+	}
+	return "run `" + s.Run + "`"
+}
+
+// validateHooks rejects steps that are not exactly one job or command.
+func validateHooks(hooks HooksConfig) error {
+	check := func(name string, steps []HookStep) error {
+		for i, step := range steps {
+			if (step.Job == "") == (step.Run == "") {
+				return fmt.Errorf("deploy.hooks.%s[%d]: set either job or run", name, i)
+			}
+		}
+		return nil
+	}
+	return errors.Join(
+		check("pre_rollout", hooks.PreRollout),
+		check("post_rollout", hooks.PostRollout),
+		check("on_failure", hooks.OnFailure),
+	)
+}
+
+// hookJobs returns the job services the hooks run.
+func hookJobs(hooks HooksConfig) []string {
+	var jobs []string
+	for _, steps := range [][]HookStep{hooks.PreRollout, hooks.PostRollout, hooks.OnFailure} {
+		for _, step := range steps {
+			if step.Job != "" {
+				jobs = append(jobs, step.Job)
+			}
+		}
+	}
+	return jobs
+}
+
+// runHookCommand runs the command of a hook step with sh, from the working
+// directory of the CLI and with the env block exported.
+var runHookCommand = func(command string) error {
+	cmd := []string{"sh", "-c", command}
+	if dryRun {
+		printDryRunCommand(cmd)
+		return nil
+	}
+	process := exec.Command(cmd[0], cmd[1:]...)
+	process.Stdout = os.Stdout
+	process.Stderr = os.Stderr
+	return process.Run()
+}
+
+// runHooks runs the steps of a hook in order and stops at the first one that
+// fails. Jobs run at the images of the deployment.
+func runHooks(name, stack string, steps []HookStep, images map[string]string, jobTimeout time.Duration) error {
+	for i, step := range steps {
+		fmt.Printf("🔹 Hook %s %d/%d: %s\n", name, i+1, len(steps), step)
+		var err error
+		if step.Job != "" {
+			err = runJobs(stack, images, []string{step.Job}, jobTimeout)
+		} else {
+			err = runHookCommand(step.Run)
+		}
+		if err != nil {
+			return fmt.Errorf("hook %s: %s: %w", name, step, err)
+		}
+	}
+	return nil
+}
+
+// runFailureHooks runs the on_failure hook after a rollout failed with
+// cause. Its own failures are only reported, so that cause is what the
+// rollout fails with.
+func runFailureHooks(stack string, steps []HookStep, images map[string]string, jobTimeout time.Duration, cause error) {
+	if len(steps) == 0 {
+		return
+	}
+	os.Setenv(rolloutErrorEnv, cause.Error())
+	defer os.Unsetenv(rolloutErrorEnv)
+	if err := runHooks("on_failure", stack, steps, images, jobTimeout); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
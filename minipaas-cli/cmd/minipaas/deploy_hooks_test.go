@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	data := `deploy:
+  version: 1.0.0
+  hooks:
+    pre_rollout:
+      - job: migrate
+    post_rollout:
+      - run: ./smoke.sh
+    on_failure:
+      - run: ./notify.sh
+  waves:
+    - [api]
+    - [worker, emails]
+`
+	if err := os.WriteFile(filepath.Join(dir, "minipaas.yaml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := loadConfig(dir)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	hooks := cfg.Deploy.Hooks
+	if len(hooks.PreRollout) != 1 || hooks.PreRollout[0].Job != "migrate" || hooks.PostRollout[0].Run != "./smoke.sh" || hooks.OnFailure[0].Run != "./notify.sh" {
+		t.Fatalf("unexpected hooks: %#v", hooks)
+	}
+	if len(cfg.Deploy.Waves) != 2 || !slices.Equal(cfg.Deploy.Waves[1], []string{"worker", "emails"}) {
+		t.Fatalf("unexpected waves: %#v", cfg.Deploy.Waves)
+	}
+	if jobs := hookJobs(hooks); !slices.Equal(jobs, []string{"migrate"}) {
+		t.Fatalf("unexpected hook jobs: %v", jobs)
+	}
+
+	// Hooks and waves are left out of configs that do not set them.
+	fn, err := saveConfig(dir, Config{Deploy: DeployConfig{Version: "1.0.0"}})
+	if err != nil {
+		t.Fatalf("saveConfig: %v", err)
+	}
+	saved, _ := os.ReadFile(fn)
+	if strings.Contains(string(saved), "hooks") || strings.Contains(string(saved), "waves") {
+		t.Fatalf("unexpected hooks or waves in:\n%s", saved)
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	if err := validateHooks(HooksConfig{PreRollout: []HookStep{{Job: "migrate"}, {Run: "true"}}}); err != nil {
+		t.Fatalf("validateHooks: %v", err)
+	}
+	for _, step := range []HookStep{{}, {Job: "migrate", Run: "true"}} {
+		err := validateHooks(HooksConfig{OnFailure: []HookStep{step}})
+		if err == nil || !strings.Contains(err.Error(), "deploy.hooks.on_failure[0]") {
+			t.Fatalf("expected an error for %#v, got %v", step, err)
+		}
+	}
+}
+
+func TestRunHooks_StopsAtFirstFailure(t *testing.T) {
+	orig := runHookCommand
+	t.Cleanup(func() { runHookCommand = orig })
+	var ran []string
+	runHookCommand = func(command string) error {
+		ran = append(ran, command)
+		if command == "fail" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	steps := []HookStep{{Run: "first"}, {Run: "fail"}, {Run: "never"}}
+	err := runHooks("pre_rollout", "minipaas", steps, nil, time.Minute)
+	if err == nil || err.Error() != "hook pre_rollout: run `fail`: exit status 1" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(ran, []string{"first", "fail"}) {
+		t.Fatalf("unexpected commands: %v", ran)
+	}
+}
+
+func TestRunHooks_UnknownJob(t *testing.T) {
+	stubJobHooks(t)
+	var created []string
+	dockerServiceCreate = func(spec any) error {
+		name, _ := serviceSpecSummary(spec)
+		created = append(created, name)
+		return nil
+	}
+
+	// Hook jobs must be services of the deployment.
+	err := runHooks("post_rollout", "minipaas", []HookStep{{Job: "migrate"}}, map[string]string{}, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "not found in project") || len(created) > 0 {
+		t.Fatalf("expected a missing service error, got %v (created %v)", err, created)
+	}
+}
+
+func TestRunFailureHooks(t *testing.T) {
+	orig := runHookCommand
+	t.Cleanup(func() { runHookCommand = orig })
+	var got string
+	runHookCommand = func(command string) error {
+		got = os.Getenv(rolloutErrorEnv)
+		return errors.New("notify failed")
+	}
+
+	runFailureHooks("minipaas", []HookStep{{Run: "./notify.sh"}}, nil, time.Minute, errors.New("wave 2: api paused"))
+	if got != "wave 2: api paused" {
+		t.Fatalf("unexpected %s: %q", rolloutErrorEnv, got)
+	}
+	if os.Getenv(rolloutErrorEnv) != "" {
+		t.Fatalf("%s left set", rolloutErrorEnv)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+const (
+	jobSuffix = "_job"
+	// jobLabel marks the one-off service of a job run with the name of the
+	// stack service it runs.
+	jobLabel = "minipaas.job.of"
+)
+
+// jobLogGrace is how long the logs of a finished job may keep streaming
+// before they are cut.
+var jobLogGrace = 2 * time.Second
+
+// jobSpec derives a replicated-job service from a live job service: a single
+// task at the given image, never restarted, so its exit code is final.
+func jobSpec(raw json.RawMessage, live, image string) (map[string]any, error) {
+	spec, err := siblingSpec(raw, live, live+jobSuffix, image, jobLabel)
+	if err != nil {
+		return nil, err
+	}
+	one := uint64(1)
+	spec["Mode"] = map[string]any{"ReplicatedJob": map[string]any{"MaxConcurrent": one, "TotalCompletions": one}}
+	template := spec["TaskTemplate"].(map[string]any)
+	template["RestartPolicy"] = map[string]any{"Condition": "none"}
+	// Jobs are not updated in place.
+	delete(spec, "UpdateConfig")
+	delete(spec, "RollbackConfig")
+	return spec, nil
+}
+
+// jobOutcome reads the state of the last task of a job run. done is false
+// while the task has not reached a terminal state; err is set when it ended
+// without running to completion.
+func jobOutcome(tasks []dockerapi.Task) (done bool, code int, err error) {
+	if len(tasks) == 0 {
+		return false, 0, nil
+	}
+	last := tasks[0]
+	for _, t := range tasks[1:] {
+		if t.CreatedAt.After(last.CreatedAt) {
+			last = t
+		}
+	}
+
+	status := last.Status
+	switch status.State {
+	case dockerapi.TaskStateComplete:
+		return true, 0, nil
+	case dockerapi.TaskStateFailed:
+		code = 1
+		if status.ContainerStatus != nil && status.ContainerStatus.ExitCode != 0 {
+			code = status.ContainerStatus.ExitCode
+		}
+		return true, code, nil
+	case dockerapi.TaskStateRejected, dockerapi.TaskStateShutdown, dockerapi.TaskStateOrphaned, dockerapi.TaskStateRemove:
+		msg := status.Err
+		if msg == "" {
+			msg = status.Message
+		}
+		return true, -1, fmt.Errorf("task %s: %s", status.State, msg)
+	}
+	return false, 0, nil
+}
+
+// runJob runs a job service of stack once at image as a one-off
+// replicated-job next to it, streams its logs to out and returns the exit
+// code of its task. The one-off service is removed once the task ended; on
+// timeout it is left running.
+func runJob(stack, service, image string, timeout time.Duration, out io.Writer) (int, error) {
+	liveName := stackService(stack, service)
+	live, err := dockerServiceInspect(liveName)
+	if err != nil {
+		return -1, fmt.Errorf("inspect %s (deploy the stack once before running its jobs): %w", liveName, err)
+	}
+	name := liveName + jobSuffix
+	if _, err = dockerServiceInspect(name); err == nil {
+		return -1, fmt.Errorf("%s already exists: a run is in progress or was interrupted, remove it with `docker service rm %s`", name, name)
+	}
+
+	spec, err := jobSpec(live.RawSpec, liveName, image)
+	if err != nil {
+		return -1, err
+	}
+	if err = dockerServiceCreate(spec); err != nil {
+		return -1, err
+	}
+	if dryRun {
+		return 0, nil
+	}
+	fmt.Fprintf(out, "🔹 Running %s: %s (timeout %s)\n", service, image, timeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		if err := dockerServiceLogs(ctx, name, out, out); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Fail to stream the logs of %s: %v", name, err)
+		}
+	}()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		states, err := dockerServiceStates(dockerapi.Filters{"name": {name}})
+		if err != nil {
+			return -1, err
+		}
+		var tasks []dockerapi.Task
+		for _, s := range states {
+			if s.Service.Spec.Name == name {
+				tasks = s.Tasks
+			}
+		}
+
+		done, code, taskErr := jobOutcome(tasks)
+		if done {
+			select {
+			case <-logsDone:
+			case <-time.After(jobLogGrace):
+			}
+			cancel()
+			if err := dockerServiceRemove(name); err != nil {
+				log.Printf("⚠️ Fail to remove %s: %v", name, err)
+			}
+			return code, taskErr
+		}
+		if time.Now().After(deadline) {
+			return -1, fmt.Errorf("%s did not finish within %s, it is left running", name, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// runJobs runs the given job services of the deployment one after the
+// other, and stops at the first one that does not succeed.
+func runJobs(stack string, images map[string]string, services []string, timeout time.Duration) error {
+	for _, service := range services {
+		image, ok := images[service]
+		if !ok {
+			return fmt.Errorf("job %s: service not found in project", service)
+		}
+		code, err := runJob(stack, service, image, timeout, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", service, err)
+		}
+		if code != 0 {
+			return fmt.Errorf("job %s exited with code %d", service, code)
+		}
+		fmt.Printf("✅ Job %s completed\n", service)
+	}
+	return nil
+}
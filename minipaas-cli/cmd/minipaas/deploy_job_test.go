@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+func TestJobSpec(t *testing.T) {
+	spec, err := jobSpec(json.RawMessage(liveServiceSpec), "minipaas_api", "registry:5000/api:1.1.0")
+	if err != nil {
+		t.Fatalf("jobSpec: %v", err)
+	}
+	data, _ := json.Marshal(spec)
+	var svc swarmService
+	if err := json.Unmarshal([]byte(`{"Spec":`+string(data)+`}`), &svc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if svc.Spec.Name != "minipaas_api_job" || svc.Spec.Labels[jobLabel] != "minipaas_api" || !isSiblingService(svc) {
+		t.Fatalf("unexpected name or labels: %#v", svc.Spec)
+	}
+	job := svc.Spec.Mode.ReplicatedJob
+	if svc.Spec.Mode.Replicated != nil || job == nil || *job.MaxConcurrent != 1 || *job.TotalCompletions != 1 {
+		t.Fatalf("expected a single replicated job: %#v", svc.Spec.Mode)
+	}
+	if !strings.Contains(string(data), `"RestartPolicy":{"Condition":"none"}`) {
+		t.Fatalf("expected no restarts: %s", data)
+	}
+	if svc.Spec.TaskTemplate.ContainerSpec.Image != "registry:5000/api:1.1.0" {
+		t.Fatalf("unexpected image %q", svc.Spec.TaskTemplate.ContainerSpec.Image)
+	}
+}
+
+func jobTask(state string, exitCode int, at time.Time) dockerapi.Task {
+	t := task(dockerapi.TaskStateShutdown, state, "", at)
+	t.CreatedAt = at
+	t.Status.ContainerStatus = &dockerapi.ContainerStatus{ExitCode: exitCode}
+	return t
+}
+
+func TestJobOutcome(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name  string
+		tasks []dockerapi.Task
+		done  bool
+		code  int
+		err   bool
+	}{
+		{"no task yet", nil, false, 0, false},
+		{"running", []dockerapi.Task{jobTask(dockerapi.TaskStateRunning, 0, now)}, false, 0, false},
+		{"complete", []dockerapi.Task{jobTask(dockerapi.TaskStateComplete, 0, now)}, true, 0, false},
+		{"failed", []dockerapi.Task{jobTask(dockerapi.TaskStateFailed, 3, now)}, true, 3, false},
+		{"rejected", []dockerapi.Task{jobTask(dockerapi.TaskStateRejected, 0, now)}, true, -1, true},
+		{"latest task wins", []dockerapi.Task{
+			jobTask(dockerapi.TaskStateComplete, 0, now),
+			jobTask(dockerapi.TaskStateFailed, 2, now.Add(-time.Minute)),
+		}, true, 0, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			done, code, err := jobOutcome(c.tasks)
+			if done != c.done || code != c.code || (err != nil) != c.err {
+				t.Fatalf("got done=%v code=%d err=%v", done, code, err)
+			}
+		})
+	}
+}
+
+func stubJobHooks(t *testing.T) {
+	origInspect, origCreate, origRemove := dockerServiceInspect, dockerServiceCreate, dockerServiceRemove
+	origStates, origLogs, origInterval, origGrace := dockerServiceStates, dockerServiceLogs, waitPollInterval, jobLogGrace
+	t.Cleanup(func() {
+		dockerServiceInspect, dockerServiceCreate, dockerServiceRemove = origInspect, origCreate, origRemove
+		dockerServiceStates, dockerServiceLogs, waitPollInterval, jobLogGrace = origStates, origLogs, origInterval, origGrace
+	})
+	waitPollInterval, jobLogGrace = time.Millisecond, time.Second
+
+	dockerServiceInspect = func(service string) (swarmService, error) {
+		if service != "minipaas_migrate" {
+			return swarmService{}, &dockerapi.Error{StatusCode: 404, Message: "not found"}
+		}
+		svc := swarmService{RawSpec: json.RawMessage(strings.Replace(liveServiceSpec, "minipaas_api", "minipaas_migrate", 1))}
+		svc.Spec.Name = service
+		return svc, nil
+	}
+	dockerServiceLogs = func(_ context.Context, service string, stdout, _ io.Writer) error {
+		_, err := io.WriteString(stdout, "migrating "+service+"\n")
+		return err
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	stubJobHooks(t)
+
+	var created, removed string
+	dockerServiceCreate = func(spec any) error {
+		created, _ = serviceSpecSummary(spec)
+		return nil
+	}
+	dockerServiceRemove = func(service string) error {
+		removed = service
+		return nil
+	}
+	polls := 0
+	dockerServiceStates = func(f dockerapi.Filters) ([]stackServiceState, error) {
+		polls++
+		state := dockerapi.TaskStateRunning
+		if polls > 2 {
+			state = dockerapi.TaskStateFailed
+		}
+		return []stackServiceState{
+			replicatedState("minipaas_migrate_job", 1, "", jobTask(state, 5, time.Now())),
+			replicatedState("minipaas_migrate_job2", 1, "", jobTask(dockerapi.TaskStateComplete, 0, time.Now())),
+		}, nil
+	}
+
+	var out bytes.Buffer
+	code, err := runJob("minipaas", "migrate", "registry:5000/migrate:1.1.0", time.Second, &out)
+	if err != nil {
+		t.Fatalf("runJob: %v", err)
+	}
+	if code != 5 {
+		t.Fatalf("expected exit code 5, got %d", code)
+	}
+	if created != "minipaas_migrate_job" || removed != "minipaas_migrate_job" {
+		t.Fatalf("unexpected created %q or removed %q", created, removed)
+	}
+	if !strings.Contains(out.String(), "migrating minipaas_migrate_job") {
+		t.Fatalf("expected job logs, got %q", out.String())
+	}
+}
+
+func TestRunJob_RefusesExistingRun(t *testing.T) {
+	stubJobHooks(t)
+	inspect := dockerServiceInspect
+	dockerServiceInspect = func(service string) (swarmService, error) {
+		if service == "minipaas_migrate_job" {
+			return swarmService{}, nil
+		}
+		return inspect(service)
+	}
+	dockerServiceCreate = func(spec any) error {
+		t.Fatalf("unexpected create")
+		return nil
+	}
+
+	_, err := runJob("minipaas", "migrate", "registry:5000/migrate:1.1.0", time.Second, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "docker service rm minipaas_migrate_job") {
+		t.Fatalf("expected an error about the existing run, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// stackLabelPrefix starts the labels docker stack deploy sets on the objects
+// of a stack.
+const stackLabelPrefix = "com.docker.stack."
+
+// isSiblingService reports whether svc is a canary, blue/green or job service
+// created next to a stack service rather than by the stack itself.
+func isSiblingService(svc swarmService) bool {
+	return svc.Spec.Labels[canaryLabel] != "" || svc.Spec.Labels[bluegreenLabel] != "" || svc.Spec.Labels[jobLabel] != ""
+}
+
+// siblingSpec derives the spec of a service running next to a live one from
+// its raw spec, so it runs with the same secrets, configs, networks, mode and
+// resources. The sibling gets its own name and image and is labelled with
+// the live service name; published ports and network aliases are dropped so
+// it only receives the traffic Caddy sends to it, and the stack labels are
+// dropped so docker stack deploy does not treat it as part of the stack.
+func siblingSpec(raw json.RawMessage, live, name, image, label string) (map[string]any, error) {
+	var spec map[string]any
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	spec["Name"] = name
+
+	labels, _ := spec["Labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+	}
+	dropStackLabels(labels)
+	labels[label] = live
+	spec["Labels"] = labels
+
+	template, _ := spec["TaskTemplate"].(map[string]any)
+	if template == nil {
+		return nil, fmt.Errorf("service %s has no task template", live)
+	}
+	container, _ := template["ContainerSpec"].(map[string]any)
+	if container == nil {
+		return nil, fmt.Errorf("service %s has no container spec", live)
+	}
+	container["Image"] = image
+	if labels, ok := container["Labels"].(map[string]any); ok {
+		dropStackLabels(labels)
+	}
+
+	networks, _ := template["Networks"].([]any)
+	for _, n := range networks {
+		if network, ok := n.(map[string]any); ok {
+			delete(network, "Aliases")
+		}
+	}
+
+	if endpoint, ok := spec["EndpointSpec"].(map[string]any); ok {
+		delete(endpoint, "Ports")
+	}
+	return spec, nil
+}
+
+// dropStackLabels removes the labels docker stack deploy manages.
+func dropStackLabels(labels map[string]any) {
+	for k := range labels {
+		if strings.HasPrefix(k, stackLabelPrefix) {
+			delete(labels, k)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// defaultObjectMode is the mode docker stack deploy mounts secrets and
+// configs with when the compose file does not set one.
+const defaultObjectMode = 0o444
+
+// composeSpec returns the raw spec of a live service with the settings of its
+// compose service applied the way docker stack deploy applies them: image,
+// entrypoint and command, environment, labels, healthcheck, secrets, configs
+// and resources. Networks, mode, placement and update settings are kept from
+// the live spec.
+func composeSpec(raw json.RawMessage, project *types.Project, svc types.ServiceConfig) (json.RawMessage, error) {
+	var spec map[string]any
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	template, _ := spec["TaskTemplate"].(map[string]any)
+	if template == nil {
+		return nil, fmt.Errorf("service %s has no task template", svc.Name)
+	}
+	container, _ := template["ContainerSpec"].(map[string]any)
+	if container == nil {
+		return nil, fmt.Errorf("service %s has no container spec", svc.Name)
+	}
+
+	container["Image"] = svc.Image
+	setOrDelete(container, "Command", []string(svc.Entrypoint), len(svc.Entrypoint) > 0)
+	setOrDelete(container, "Args", []string(svc.Command), len(svc.Command) > 0)
+	setOrDelete(container, "Env", composeEnv(svc.Environment), len(svc.Environment) > 0)
+	containerLabels := withStackLabels(container["Labels"], svc.Labels)
+	setOrDelete(container, "Labels", containerLabels, len(containerLabels) > 0)
+	healthcheck := composeHealthcheck(svc.HealthCheck)
+	setOrDelete(container, "Healthcheck", healthcheck, healthcheck != nil)
+
+	secrets, err := composeSecretRefs(project, svc)
+	if err != nil {
+		return nil, err
+	}
+	setOrDelete(container, "Secrets", secrets, len(secrets) > 0)
+	configs, err := composeConfigRefs(project, svc)
+	if err != nil {
+		return nil, err
+	}
+	setOrDelete(container, "Configs", configs, len(configs) > 0)
+
+	var labels map[string]string
+	resources := map[string]any{}
+	if d := svc.Deploy; d != nil {
+		labels = d.Labels
+		if l := d.Resources.Limits; l != nil {
+			resources["Limits"] = map[string]any{"NanoCPUs": cpusToNano(l.NanoCPUs), "MemoryBytes": int64(l.MemoryBytes), "Pids": l.Pids}
+		}
+		if r := d.Resources.Reservations; r != nil {
+			resources["Reservations"] = map[string]any{"NanoCPUs": cpusToNano(r.NanoCPUs), "MemoryBytes": int64(r.MemoryBytes)}
+		}
+	}
+	serviceLabels := withStackLabels(spec["Labels"], labels)
+	setOrDelete(spec, "Labels", serviceLabels, len(serviceLabels) > 0)
+	template["Resources"] = resources
+
+	return json.Marshal(spec)
+}
+
+// updateComposeService updates a running stack service to the spec of its
+// compose service in deployment. It returns once the update is accepted.
+func updateComposeService(stack string, deployment *types.Project, service string) error {
+	name := stackService(stack, service)
+	live, err := dockerServiceInspect(name)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", name, err)
+	}
+	srv, err := deployment.GetService(service)
+	if err != nil {
+		return err
+	}
+	spec, err := composeSpec(live.RawSpec, deployment, srv)
+	if err != nil {
+		return err
+	}
+	if err = dockerServiceUpdateSpec(name, live.Version.Index, spec); err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	return nil
+}
+
+// withStackLabels returns the labels of a compose service on top of the
+// labels docker stack deploy set on the live spec, so the service stays part
+// of its stack.
+func withStackLabels(live any, labels map[string]string) map[string]any {
+	out := map[string]any{}
+	current, _ := live.(map[string]any)
+	for k, v := range current {
+		if strings.HasPrefix(k, stackLabelPrefix) {
+			out[k] = v
+		}
+	}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+func setOrDelete(m map[string]any, key string, value any, set bool) {
+	if set {
+		m[key] = value
+	} else {
+		delete(m, key)
+	}
+}
+
+// composeEnv renders an environment as sorted KEY=value entries; variables
+// without a value are passed by name.
+func composeEnv(env types.MappingWithEquals) []string {
+	var out []string
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		if v := env[k]; v != nil {
+			out = append(out, k+"="+*v)
+		} else {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func composeHealthcheck(hc *types.HealthCheckConfig) map[string]any {
+	if hc == nil {
+		return nil
+	}
+	if hc.Disable {
+		return map[string]any{"Test": []string{"NONE"}}
+	}
+	out := map[string]any{}
+	if len(hc.Test) > 0 {
+		out["Test"] = []string(hc.Test)
+	}
+	for key, d := range map[string]*types.Duration{
+		"Interval":      hc.Interval,
+		"Timeout":       hc.Timeout,
+		"StartPeriod":   hc.StartPeriod,
+		"StartInterval": hc.StartInterval,
+	} {
+		if d != nil {
+			out[key] = int64(time.Duration(*d))
+		}
+	}
+	if hc.Retries != nil {
+		out["Retries"] = *hc.Retries
+	}
+	return out
+}
+
+// composeSecretRefs resolves the secrets a compose service mounts to the
+// Swarm secrets they name.
+func composeSecretRefs(project *types.Project, svc types.ServiceConfig) ([]map[string]any, error) {
+	var refs []map[string]any
+	names := map[string]string{}
+	for _, s := range svc.Secrets {
+		name := s.Source
+		if decl, ok := project.Secrets[s.Source]; ok && decl.Name != "" {
+			name = decl.Name
+		}
+		names[s.Source] = name
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids, err := dockerSecretIDs(slices.Sorted(maps.Values(names)))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range svc.Secrets {
+		name := names[s.Source]
+		if ids[name] == "" {
+			return nil, fmt.Errorf("secret %s of service %s not found", name, svc.Name)
+		}
+		refs = append(refs, map[string]any{
+			"File":       fileTarget(secretMountPath(s.Target, s.Source), s.UID, s.GID, s.Mode),
+			"SecretID":   ids[name],
+			"SecretName": name,
+		})
+	}
+	return refs, nil
+}
+
+// composeConfigRefs resolves the configs a compose service mounts to the
+// Swarm configs they name.
+func composeConfigRefs(project *types.Project, svc types.ServiceConfig) ([]map[string]any, error) {
+	var refs []map[string]any
+	names := map[string]string{}
+	for _, c := range svc.Configs {
+		name := c.Source
+		if decl, ok := project.Configs[c.Source]; ok && decl.Name != "" {
+			name = decl.Name
+		}
+		names[c.Source] = name
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids, err := dockerConfigIDs(slices.Sorted(maps.Values(names)))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range svc.Configs {
+		name := names[c.Source]
+		if ids[name] == "" {
+			return nil, fmt.Errorf("config %s of service %s not found", name, svc.Name)
+		}
+		refs = append(refs, map[string]any{
+			"File":       fileTarget(configMountPath(c.Target, c.Source), c.UID, c.GID, c.Mode),
+			"ConfigID":   ids[name],
+			"ConfigName": name,
+		})
+	}
+	return refs, nil
+}
+
+func fileTarget(path, uid, gid string, mode *types.FileMode) map[string]any {
+	if uid == "" {
+		uid = "0"
+	}
+	if gid == "" {
+		gid = "0"
+	}
+	m := uint32(defaultObjectMode)
+	if mode != nil {
+		m = uint32(*mode)
+	}
+	return map[string]any{"Name": path, "UID": uid, "GID": gid, "Mode": m}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+func TestComposeSpec(t *testing.T) {
+	origSecrets, origConfigs := dockerSecretIDs, dockerConfigIDs
+	t.Cleanup(func() { dockerSecretIDs, dockerConfigIDs = origSecrets, origConfigs })
+	dockerSecretIDs = func(names []string) (map[string]string, error) {
+		return map[string]string{"db.b1946ac9": "s2"}, nil
+	}
+	dockerConfigIDs = func(names []string) (map[string]string, error) {
+		return map[string]string{}, nil
+	}
+
+	value := "postgres://db"
+	interval := types.Duration(5 * time.Second)
+	mode := types.FileMode(0o400)
+	project := &types.Project{
+		Secrets: types.Secrets{"db": {Name: "db.b1946ac9", External: true}},
+	}
+	svc := types.ServiceConfig{
+		Name:        "api",
+		Image:       "registry:5000/api:1.1.0",
+		Environment: types.MappingWithEquals{"DATABASE_URL": &value, "DEBUG": nil},
+		Secrets:     []types.ServiceSecretConfig{{Source: "db", Mode: &mode}},
+		HealthCheck: &types.HealthCheckConfig{Test: types.HealthCheckTest{"CMD", "true"}, Interval: &interval},
+		Deploy: &types.DeployConfig{
+			Labels:    types.Labels{"team": "core"},
+			Resources: types.Resources{Limits: &types.Resource{MemoryBytes: 64 << 20}},
+		},
+	}
+
+	raw, err := composeSpec(json.RawMessage(liveServiceSpec), project, svc)
+	if err != nil {
+		t.Fatalf("composeSpec: %v", err)
+	}
+	var s swarmService
+	if err := json.Unmarshal([]byte(`{"Spec":`+string(raw)+`}`), &s); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	container := s.Spec.TaskTemplate.ContainerSpec
+	if container.Image != svc.Image || !reflect.DeepEqual(container.Env, []string{"DATABASE_URL=postgres://db", "DEBUG"}) {
+		t.Fatalf("unexpected container spec: %#v", container)
+	}
+	if len(container.Secrets) != 1 || container.Secrets[0].SecretID != "s2" ||
+		container.Secrets[0].File.Name != "/run/secrets/db" || container.Secrets[0].File.Mode != 0o400 {
+		t.Fatalf("unexpected secrets: %#v", container.Secrets)
+	}
+	if container.Healthcheck == nil || container.Healthcheck.Test[1] != "true" {
+		t.Fatalf("unexpected healthcheck: %#v", container.Healthcheck)
+	}
+	// the labels of docker stack deploy are kept, the other ones follow compose
+	if s.Spec.Labels["com.docker.stack.namespace"] != "minipaas" || container.Labels["com.docker.stack.namespace"] != "minipaas" ||
+		container.Labels["app"] != "" {
+		t.Fatalf("unexpected labels: %v / %v", s.Spec.Labels, container.Labels)
+	}
+	if s.Spec.Labels["team"] != "core" || s.Spec.TaskTemplate.Resources.Limits.MemoryBytes != 64<<20 {
+		t.Fatalf("unexpected labels or resources: %#v", s.Spec)
+	}
+	// settings compose does not describe here are kept from the live spec
+	if r := s.Spec.Mode.Replicated; r == nil || *r.Replicas != 2 || s.Spec.TaskTemplate.Networks[0].Target != "net1" {
+		t.Fatalf("expected live mode and networks kept: %#v", s.Spec)
+	}
+
+	svc.Secrets = []types.ServiceSecretConfig{{Source: "missing"}}
+	if _, err = composeSpec(json.RawMessage(liveServiceSpec), project, svc); err == nil {
+		t.Fatalf("expected error for a secret missing from the swarm")
+	}
+}
+
+func TestUpdateComposeService(t *testing.T) {
+	origInspect, origUpdate := dockerServiceInspect, dockerServiceUpdateSpec
+	t.Cleanup(func() { dockerServiceInspect, dockerServiceUpdateSpec = origInspect, origUpdate })
+
+	dockerServiceInspect = func(name string) (swarmService, error) {
+		return swarmService{Version: dockerapi.Version{Index: 7}, RawSpec: json.RawMessage(liveServiceSpec)}, nil
+	}
+	var name string
+	var version uint64
+	var spec dockerapi.ServiceSpec
+	dockerServiceUpdateSpec = func(service string, v uint64, s any) error {
+		name, version = service, v
+		return json.Unmarshal(s.(json.RawMessage), &spec)
+	}
+
+	deployment := &types.Project{Services: types.Services{"api": {Name: "api", Image: "registry:5000/api:1.1.0"}}}
+	if err := updateComposeService("minipaas", deployment, "api"); err != nil {
+		t.Fatalf("updateComposeService: %v", err)
+	}
+	if name != "minipaas_api" || version != 7 || spec.TaskTemplate.ContainerSpec.Image != "registry:5000/api:1.1.0" {
+		t.Fatalf("unexpected update of %s at %d: %#v", name, version, spec)
+	}
+	// the promoted service must stay in its stack
+	if spec.Labels["com.docker.stack.namespace"] != "minipaas" {
+		t.Fatalf("stack labels lost: %v", spec.Labels)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	versionStrategyGit    = "git"
+	versionStrategySemver = "semver"
+	versionStrategyDate   = "date"
+)
+
+// Make external calls overridable for tests
+// gitOutput runs git in dir, so that the repository is the one holding the
+// environment rather than the working directory of the CLI.
+var gitOutput = func(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+var versionNow = time.Now
+
+// nextVersion computes the version that follows current with the given
+// strategy. part selects the semver component to bump; the git strategy
+// reads the repository of env.
+func nextVersion(env, strategy, current, part string) (string, error) {
+	switch strategy {
+	case versionStrategyGit:
+		return gitVersion(env)
+	case versionStrategySemver:
+		return bumpSemver(current, part)
+	case versionStrategyDate:
+		return versionNow().UTC().Format("20060102-150405"), nil
+	}
+	return "", fmt.Errorf("unknown strategy %q: use git, semver or date", strategy)
+}
+
+// gitVersion returns the short SHA of HEAD. A dirty worktree is refused, as
+// the image would not match the commit it is named after.
+func gitVersion(dir string) (string, error) {
+	status, err := gitOutput(dir, "status", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("git status: %w", err)
+	}
+	if status != "" {
+		return "", errors.New("worktree has uncommitted changes, commit or stash them first")
+	}
+	sha, err := gitOutput(dir, "rev-parse", "--short=12", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse: %w", err)
+	}
+	return sha, nil
+}
+
+var semverRe = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)$`)
+
+// bumpSemver increments the major, minor or patch part of current, keeping a
+// leading "v". An empty current version starts at 0.1.0.
+func bumpSemver(current, part string) (string, error) {
+	if current == "" {
+		return "0.1.0", nil
+	}
+	m := semverRe.FindStringSubmatch(current)
+	if m == nil {
+		return "", fmt.Errorf("current version %q is not MAJOR.MINOR.PATCH", current)
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	patch, _ := strconv.Atoi(m[4])
+	switch part {
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	case "patch", "":
+		patch++
+	default:
+		return "", fmt.Errorf("unknown part %q: use major, minor or patch", part)
+	}
+	return fmt.Sprintf("%s%d.%d.%d", m[1], major, minor, patch), nil
+}
+
+// imageTag returns the tag of an image reference, ignoring any digest.
+func imageTag(image string) string {
+	if idx := strings.Index(image, "@"); idx != -1 {
+		image = image[:idx]
+	}
+	idx := strings.LastIndex(image, ":")
+	if idx == -1 || strings.Contains(image[idx:], "/") {
+		return "latest"
+	}
+	return image[idx+1:]
+}
+
+const (
+	versionStatusOK         = "ok"
+	versionStatusOutdated   = "outdated"
+	versionStatusNotRunning = "not running"
+)
+
+// runningVersion is the version a stack service runs compared to the
+// configured one.
+type runningVersion struct {
+	Service string
+	Running string
+	Status  string
+}
+
+// compareRunningVersions reports, for each service, the tag it runs in the
+// stack and whether it matches configured.
+func compareRunningVersions(configured string, services []string, stackServices []swarmService, stack string) []runningVersion {
+	var versions []runningVersion
+	for _, name := range services {
+		v := runningVersion{Service: name, Running: "-", Status: versionStatusNotRunning}
+		if svc, ok := findSwarmService(stackServices, stackService(stack, name)); ok {
+			v.Running = imageTag(svc.Spec.TaskTemplate.ContainerSpec.Image)
+			v.Status = versionStatusOK
+			if v.Running != configured {
+				v.Status = versionStatusOutdated
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+func printRunningVersions(w io.Writer, versions []runningVersion) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tRUNNING\tSTATUS")
+	for _, v := range versions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Service, v.Running, v.Status)
+	}
+	return tw.Flush()
+}
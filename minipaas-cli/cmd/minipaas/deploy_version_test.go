@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBumpSemver(t *testing.T) {
+	cases := []struct{ current, part, want string }{
+		{"", "", "0.1.0"},
+		{"1.2.3", "", "1.2.4"},
+		{"1.2.3", "minor", "1.3.0"},
+		{"v1.2.3", "major", "v2.0.0"},
+	}
+	for _, c := range cases {
+		got, err := bumpSemver(c.current, c.part)
+		if err != nil || got != c.want {
+			t.Fatalf("bumpSemver(%q, %q) = %q, %v; want %q", c.current, c.part, got, err, c.want)
+		}
+	}
+	if _, err := bumpSemver("abc1234", ""); err == nil {
+		t.Fatalf("expected error for non semver version")
+	}
+	if _, err := bumpSemver("1.2.3", "build"); err == nil {
+		t.Fatalf("expected error for unknown part")
+	}
+}
+
+func TestNextVersion_GitAndDate(t *testing.T) {
+	origGit, origNow := gitOutput, versionNow
+	t.Cleanup(func() { gitOutput, versionNow = origGit, origNow })
+
+	status := ""
+	var dirs []string
+	gitOutput = func(dir string, args ...string) (string, error) {
+		dirs = append(dirs, dir)
+		switch args[0] {
+		case "status":
+			return status, nil
+		case "rev-parse":
+			return "0123456789ab", nil
+		}
+		return "", errors.New("unexpected git call")
+	}
+	got, err := nextVersion("envs/prod", versionStrategyGit, "1.0.0", "")
+	if err != nil || got != "0123456789ab" {
+		t.Fatalf("git version = %q, %v", got, err)
+	}
+	if len(dirs) != 2 || dirs[0] != "envs/prod" || dirs[1] != "envs/prod" {
+		t.Fatalf("git should run in the env directory, ran in %v", dirs)
+	}
+	status = " M main.go"
+	if _, err = nextVersion("envs/prod", versionStrategyGit, "1.0.0", ""); err == nil {
+		t.Fatalf("expected error for dirty worktree")
+	}
+
+	versionNow = func() time.Time { return time.Date(2026, 10, 18, 9, 30, 5, 0, time.UTC) }
+	got, err = nextVersion("envs/prod", versionStrategyDate, "", "")
+	if err != nil || got != "20261018-093005" {
+		t.Fatalf("date version = %q, %v", got, err)
+	}
+
+	if _, err = nextVersion("envs/prod", "calver", "", ""); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	cases := map[string]string{
+		"registry:5000/api:1.2.3@sha256:abc": "1.2.3",
+		"registry:5000/api":                  "latest",
+		"postgres:16":                        "16",
+	}
+	for image, want := range cases {
+		if got := imageTag(image); got != want {
+			t.Fatalf("imageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestCompareRunningVersions(t *testing.T) {
+	services, err := parseServiceInspect([]byte(serviceInspectFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := compareRunningVersions("1.1.0", []string{"api", "cron"}, services, "minipaas")
+	want := []runningVersion{
+		{Service: "api", Running: "1.0.0", Status: versionStatusOutdated},
+		{Service: "cron", Running: "-", Status: versionStatusNotRunning},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions mismatch: %#v", got)
+	}
+}
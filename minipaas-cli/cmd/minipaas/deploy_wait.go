@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// stackServiceState is a stack service together with its tasks.
+type stackServiceState struct {
+	Service dockerapi.Service
+	Tasks   []dockerapi.Task
+}
+
+// Make external calls overridable for tests
+var dockerServiceStates = func(filters dockerapi.Filters) ([]stackServiceState, error) {
+	var states []stackServiceState
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		services, err := c.ServiceList(ctx, filters)
+		if err != nil {
+			return err
+		}
+		for _, svc := range services {
+			tasks, err := c.TaskList(ctx, dockerapi.Filters{"service": {svc.ID}})
+			if err != nil {
+				return err
+			}
+			states = append(states, stackServiceState{Service: svc, Tasks: tasks})
+		}
+		return nil
+	})
+	return states, err
+}
+
+// serviceConvergence summarizes how far a service is in its rollout.
+type serviceConvergence struct {
+	Name      string
+	State     string
+	Running   int
+	Completed int
+	Desired   int
+	Failed    bool
+	Done      bool
+	Errors    []string
+}
+
+func (c serviceConvergence) String() string {
+	state := c.State
+	if state == "" {
+		state = "running"
+	}
+	if c.Completed > 0 {
+		return fmt.Sprintf("%s: %s (%d/%d tasks running, %d completed)", c.Name, state, c.Running, c.Desired, c.Completed)
+	}
+	return fmt.Sprintf("%s: %s (%d/%d tasks running)", c.Name, state, c.Running, c.Desired)
+}
+
+// maxTaskErrors is how many recent task errors are reported per service.
+const maxTaskErrors = 3
+
+// evaluateService decides whether a service has converged or failed. Rolled
+// back and paused updates that started at or after since are failures; older
+// ones come from an earlier deploy and are ignored. Otherwise the service is
+// done when every desired task is running and no update is in progress. The
+// tasks of services that are not restarted once they exit, like jobs, count
+// as converged when they completed.
+func evaluateService(state stackServiceState, since time.Time) serviceConvergence {
+	svc := state.Service
+	c := serviceConvergence{Name: svc.Spec.Name}
+	if u := svc.UpdateStatus; u != nil && (u.StartedAt == nil || !u.StartedAt.Before(since)) {
+		c.State = u.State
+	}
+
+	global := svc.Spec.Mode.Global != nil
+	if r := svc.Spec.Mode.Replicated; r != nil && r.Replicas != nil {
+		c.Desired = int(*r.Replicas)
+	}
+	runsOnce := false
+	if p := svc.Spec.TaskTemplate.RestartPolicy; p != nil {
+		runsOnce = p.Condition == dockerapi.RestartConditionNone || p.Condition == dockerapi.RestartConditionOnFailure
+	}
+	starting := 0
+	for _, task := range state.Tasks {
+		if runsOnce && task.Status.State == dockerapi.TaskStateComplete {
+			c.Completed++
+			continue
+		}
+		if task.DesiredState != dockerapi.TaskStateRunning {
+			continue
+		}
+		if global {
+			c.Desired++
+		}
+		if task.Status.State == dockerapi.TaskStateRunning {
+			c.Running++
+		} else {
+			starting++
+		}
+	}
+	c.Errors = recentTaskErrors(state.Tasks, maxTaskErrors)
+
+	switch c.State {
+	case dockerapi.UpdateStateRollbackCompleted, dockerapi.UpdateStateRollbackPaused, dockerapi.UpdateStatePaused:
+		c.Failed = true
+		if svc.UpdateStatus.Message != "" {
+			c.Errors = append([]string{svc.UpdateStatus.Message}, c.Errors...)
+		}
+	case dockerapi.UpdateStateUpdating, dockerapi.UpdateStateRollbackStarted:
+	default:
+		if runsOnce {
+			c.Done = starting == 0 && c.Running+c.Completed >= c.Desired
+		} else {
+			c.Done = c.Running == c.Desired
+		}
+	}
+	return c
+}
+
+// recentTaskErrors returns the errors of the latest failed or rejected tasks,
+// newest first.
+func recentTaskErrors(tasks []dockerapi.Task, limit int) []string {
+	var failed []dockerapi.Task
+	for _, task := range tasks {
+		if task.Status.State == dockerapi.TaskStateFailed || task.Status.State == dockerapi.TaskStateRejected {
+			failed = append(failed, task)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Status.Timestamp.After(failed[j].Status.Timestamp)
+	})
+
+	var errs []string
+	for _, task := range failed {
+		if len(errs) == limit {
+			break
+		}
+		msg := task.Status.Err
+		if msg == "" {
+			msg = task.Status.Message
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", task.Status.Timestamp.Format(time.RFC3339), msg))
+	}
+	return errs
+}
+
+// Polling of waiting commands, overridable for tests.
+var (
+	waitPollInterval = 2 * time.Second
+	waitStablePeriod = 5 * time.Second
+)
+
+// waitOptions controls waitForStack polling.
+type waitOptions struct {
+	Timeout  time.Duration
+	Interval time.Duration
+	// Stable is how long every service must stay converged before the
+	// rollout is considered done, so crash-looping tasks are caught.
+	Stable time.Duration
+	// Since is when the change being waited for was made; failed updates
+	// that started before it are ignored.
+	Since time.Time
+}
+
+// waitForStack polls fetch until every service converges, one fails or the
+// timeout expires. Progress lines are written when a service changes.
+func waitForStack(fetch func() ([]stackServiceState, error), opts waitOptions, out io.Writer) error {
+	deadline := time.Now().Add(opts.Timeout)
+	last := map[string]string{}
+	var convergedSince time.Time
+	var current []serviceConvergence
+
+	for {
+		states, err := fetch()
+		if err != nil {
+			return err
+		}
+
+		current = current[:0]
+		allDone := true
+		for _, state := range states {
+			c := evaluateService(state, opts.Since)
+			current = append(current, c)
+			if line := c.String(); last[c.Name] != line {
+				fmt.Fprintf(out, "🔹 %s\n", line)
+				last[c.Name] = line
+			}
+			if c.Failed {
+				printConvergenceErrors(out, []serviceConvergence{c})
+				return fmt.Errorf("service %s: update %s", c.Name, strings.ReplaceAll(c.State, "_", " "))
+			}
+			allDone = allDone && c.Done
+		}
+
+		if allDone {
+			if convergedSince.IsZero() {
+				convergedSince = time.Now()
+			}
+			if time.Since(convergedSince) >= opts.Stable {
+				return nil
+			}
+		} else {
+			convergedSince = time.Time{}
+		}
+
+		if time.Now().After(deadline) {
+			var pending []serviceConvergence
+			for _, c := range current {
+				if !c.Done {
+					pending = append(pending, c)
+				}
+			}
+			if len(pending) == 0 {
+				return fmt.Errorf("timed out after %s before the services stayed converged for %s", opts.Timeout, opts.Stable)
+			}
+			printConvergenceErrors(out, pending)
+			return fmt.Errorf("timed out after %s waiting for %d service(s) to converge", opts.Timeout, len(pending))
+		}
+		time.Sleep(opts.Interval)
+	}
+}
+
+func printConvergenceErrors(out io.Writer, services []serviceConvergence) {
+	for _, c := range services {
+		if len(c.Errors) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "❌ %s, last task errors:\n", c.Name)
+		for _, e := range c.Errors {
+			fmt.Fprintf(out, "   %s\n", e)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+func replicatedState(name string, replicas uint64, update string, tasks ...dockerapi.Task) stackServiceState {
+	svc := dockerapi.Service{}
+	svc.Spec.Name = name
+	svc.Spec.Mode.Replicated = &dockerapi.ReplicatedService{Replicas: &replicas}
+	if update != "" {
+		svc.UpdateStatus = &dockerapi.UpdateStatus{State: update, Message: "update " + update}
+	}
+	return stackServiceState{Service: svc, Tasks: tasks}
+}
+
+func task(desired, state, errMsg string, at time.Time) dockerapi.Task {
+	return dockerapi.Task{
+		DesiredState: desired,
+		Status:       dockerapi.TaskStatus{State: state, Err: errMsg, Timestamp: at},
+	}
+}
+
+func TestEvaluateService(t *testing.T) {
+	now := time.Now()
+	running := task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", now)
+	starting := task(dockerapi.TaskStateRunning, dockerapi.TaskStateStarting, "", now)
+	failed := task(dockerapi.TaskStateShutdown, dockerapi.TaskStateFailed, "exit 1", now)
+
+	c := evaluateService(replicatedState("minipaas_api", 2, "", running, running, failed), time.Time{})
+	if !c.Done || c.Failed || c.Running != 2 || c.Desired != 2 {
+		t.Fatalf("unexpected convergence %+v", c)
+	}
+
+	c = evaluateService(replicatedState("minipaas_api", 2, "", running, starting), time.Time{})
+	if c.Done || c.Running != 1 {
+		t.Fatalf("expected service still converging, got %+v", c)
+	}
+
+	c = evaluateService(replicatedState("minipaas_api", 1, dockerapi.UpdateStateUpdating, running), time.Time{})
+	if c.Done {
+		t.Fatalf("service with update in progress must not be done")
+	}
+
+	for _, state := range []string{dockerapi.UpdateStateRollbackCompleted, dockerapi.UpdateStatePaused} {
+		c = evaluateService(replicatedState("minipaas_api", 1, state, running, failed), time.Time{})
+		if !c.Failed {
+			t.Fatalf("expected %s to fail the rollout", state)
+		}
+		if len(c.Errors) != 2 || c.Errors[0] != "update "+state || !strings.Contains(c.Errors[1], "exit 1") {
+			t.Fatalf("unexpected errors %v", c.Errors)
+		}
+	}
+}
+
+func TestEvaluateService_CompletedJob(t *testing.T) {
+	now := time.Now()
+	completed := task(dockerapi.TaskStateShutdown, dockerapi.TaskStateComplete, "", now)
+	starting := task(dockerapi.TaskStateRunning, dockerapi.TaskStateStarting, "", now)
+
+	state := replicatedState("minipaas_migration", 1, "", completed)
+	state.Service.Spec.TaskTemplate.RestartPolicy = &dockerapi.RestartPolicy{Condition: dockerapi.RestartConditionOnFailure}
+	c := evaluateService(state, time.Time{})
+	if !c.Done || c.Completed != 1 || c.String() != "minipaas_migration: running (0/1 tasks running, 1 completed)" {
+		t.Fatalf("expected completed job to be converged, got %+v", c)
+	}
+
+	// a new run still starting is waited for
+	state.Tasks = append(state.Tasks, starting)
+	if c = evaluateService(state, time.Time{}); c.Done {
+		t.Fatalf("expected job with a starting task to be converging, got %+v", c)
+	}
+
+	// services restarted on any exit must keep their tasks running
+	state = replicatedState("minipaas_api", 1, "", completed)
+	if c = evaluateService(state, time.Time{}); c.Done {
+		t.Fatalf("expected exited service not to be converged, got %+v", c)
+	}
+}
+
+func TestEvaluateService_IgnoresEarlierUpdates(t *testing.T) {
+	now := time.Now()
+	running := task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", now)
+
+	for _, update := range []string{dockerapi.UpdateStateRollbackCompleted, dockerapi.UpdateStatePaused} {
+		state := replicatedState("minipaas_api", 1, update, running)
+		earlier := now.Add(-time.Hour)
+		state.Service.UpdateStatus.StartedAt = &earlier
+		if c := evaluateService(state, now); c.Failed || !c.Done {
+			t.Fatalf("expected %s of an earlier deploy to be ignored, got %+v", update, c)
+		}
+
+		later := now.Add(time.Second)
+		state.Service.UpdateStatus.StartedAt = &later
+		if c := evaluateService(state, now); !c.Failed {
+			t.Fatalf("expected %s of this deploy to fail, got %+v", update, c)
+		}
+	}
+}
+
+func TestRecentTaskErrors(t *testing.T) {
+	now := time.Now()
+	tasks := []dockerapi.Task{
+		task(dockerapi.TaskStateShutdown, dockerapi.TaskStateFailed, "first", now.Add(-3*time.Second)),
+		task(dockerapi.TaskStateShutdown, dockerapi.TaskStateRejected, "", now.Add(-time.Second)),
+		task(dockerapi.TaskStateShutdown, dockerapi.TaskStateFailed, "second", now.Add(-2*time.Second)),
+		task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", now),
+	}
+	tasks[1].Status.Message = "no suitable node"
+
+	errs := recentTaskErrors(tasks, 2)
+	if len(errs) != 2 || !strings.HasSuffix(errs[0], "no suitable node") || !strings.HasSuffix(errs[1], "second") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestWaitForStack(t *testing.T) {
+	now := time.Now()
+	running := task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", now)
+	starting := task(dockerapi.TaskStateRunning, dockerapi.TaskStateStarting, "", now)
+	failed := task(dockerapi.TaskStateShutdown, dockerapi.TaskStateFailed, "exit 1", now)
+	opts := waitOptions{Timeout: time.Second, Interval: time.Millisecond}
+
+	polls := [][]stackServiceState{
+		{replicatedState("minipaas_api", 1, dockerapi.UpdateStateUpdating, starting)},
+		{replicatedState("minipaas_api", 1, dockerapi.UpdateStateCompleted, running)},
+	}
+	calls := 0
+	fetch := func() ([]stackServiceState, error) {
+		p := polls[min(calls, len(polls)-1)]
+		calls++
+		return p, nil
+	}
+	var out bytes.Buffer
+	if err := waitForStack(fetch, opts, &out); err != nil {
+		t.Fatalf("waitForStack: %v", err)
+	}
+	if !strings.Contains(out.String(), "minipaas_api: updating (0/1 tasks running)") ||
+		!strings.Contains(out.String(), "minipaas_api: completed (1/1 tasks running)") {
+		t.Fatalf("unexpected progress output:\n%s", out.String())
+	}
+
+	out.Reset()
+	fetch = func() ([]stackServiceState, error) {
+		return []stackServiceState{replicatedState("minipaas_api", 1, dockerapi.UpdateStateRollbackCompleted, running, failed)}, nil
+	}
+	err := waitForStack(fetch, opts, &out)
+	if err == nil || !strings.Contains(err.Error(), "rollback completed") {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "exit 1") {
+		t.Fatalf("expected last task errors in output:\n%s", out.String())
+	}
+
+	out.Reset()
+	opts.Timeout = 5 * time.Millisecond
+	fetch = func() ([]stackServiceState, error) {
+		return []stackServiceState{replicatedState("minipaas_api", 1, "", starting, failed)}, nil
+	}
+	err = waitForStack(fetch, opts, &out)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(out.String(), "exit 1") {
+		t.Fatalf("expected timeout with task errors, got %v\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// validateWaves rejects empty waves and services listed in several waves.
+func validateWaves(waves [][]string) error {
+	seen := map[string]int{}
+	for i, wave := range waves {
+		if len(wave) == 0 {
+			return fmt.Errorf("deploy.waves[%d]: empty wave", i)
+		}
+		for _, service := range wave {
+			if prev, ok := seen[service]; ok {
+				return fmt.Errorf("deploy.waves[%d]: service %s is already in wave %d", i, service, prev)
+			}
+			seen[service] = i
+		}
+	}
+	return nil
+}
+
+// rolloutWaves deploys the stack. With waves configured, the running
+// services of every wave but the last one are first updated on their own, one
+// wave after the other, to the spec they have in the deployment; the stack
+// deploy then updates the last wave, the services in no wave and creates the
+// services not running yet.
+func rolloutWaves(env string, cfg Config, wait bool, timeout time.Duration, verbose bool) error {
+	waves := cfg.Deploy.Waves
+	if len(waves) == 0 {
+		return deployStack(env, cfg, wait, timeout, verbose)
+	}
+
+	composeFiles := deployComposeFiles(env, cfg)
+	deployment, err := composeLoadDeployProject(composeFiles, cfg.StackName())
+	if err != nil {
+		return err
+	}
+	for _, wave := range waves {
+		for _, service := range wave {
+			if _, ok := deployment.Services[service]; !ok {
+				return fmt.Errorf("deploy.waves: service %s not found in project", service)
+			}
+		}
+	}
+
+	last := len(waves) - 1
+	for i, wave := range waves[:last] {
+		fmt.Printf("🔹 Wave %d/%d: %v\n", i+1, len(waves), wave)
+		if err = updateWave(cfg.StackName(), deployment, wave, timeout); err != nil {
+			return fmt.Errorf("wave %d: %w", i+1, err)
+		}
+	}
+	fmt.Printf("🔹 Wave %d/%d: %v\n", len(waves), len(waves), waves[last])
+	if err = deployStack(env, cfg, wait, timeout, verbose); err != nil {
+		return fmt.Errorf("wave %d: %w", len(waves), err)
+	}
+	return nil
+}
+
+// updateWave updates the running services of a wave to the spec of their
+// compose service and waits for them to converge. Services that are not
+// running yet are left to the stack deploy.
+func updateWave(stack string, deployment *types.Project, wave []string, timeout time.Duration) error {
+	started := time.Now()
+	var updated []string
+	for _, service := range wave {
+		name := stackService(stack, service)
+		err := updateComposeService(stack, deployment, service)
+		if dockerapi.IsNotFound(err) {
+			log.Printf("⚠️ %s is not running yet, it is created with the last wave", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		updated = append(updated, name)
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	return waitForServices(stack, updated, started, timeout)
+}
+
+// waitForServices waits for the given services of the stack, changed at
+// since, to converge.
+func waitForServices(stack string, names []string, since time.Time, timeout time.Duration) error {
+	if dryRun {
+		return nil
+	}
+	fmt.Printf("🔹 Waiting for %s to converge (timeout %s)\n", strings.Join(names, ", "), timeout)
+	fetch := func() ([]stackServiceState, error) {
+		states, err := dockerServiceStates(stackFilter(stack))
+		if err != nil {
+			return nil, err
+		}
+		var wave []stackServiceState
+		for _, s := range states {
+			if slices.Contains(names, s.Service.Spec.Name) {
+				wave = append(wave, s)
+			}
+		}
+		return wave, nil
+	}
+	opts := waitOptions{Timeout: timeout, Interval: waitPollInterval, Stable: waitStablePeriod, Since: since}
+	return waitForStack(fetch, opts, os.Stdout)
+}
+
+// deployStack deploys the stack and, with wait, waits for it to converge.
+func deployStack(env string, cfg Config, wait bool, timeout time.Duration, verbose bool) error {
+	started := time.Now()
+	if err := stackDeploy(env, cfg, nil, verbose); err != nil {
+		return fmt.Errorf("deploying version %s: %w", cfg.Deploy.Version, err)
+	}
+	if wait {
+		return waitForRollout(cfg.StackName(), started, timeout)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+func TestValidateWaves(t *testing.T) {
+	if err := validateWaves([][]string{{"api"}, {"worker", "emails"}}); err != nil {
+		t.Fatalf("validateWaves: %v", err)
+	}
+	if err := validateWaves([][]string{{"api"}, {}}); err == nil || !strings.Contains(err.Error(), "empty wave") {
+		t.Fatalf("expected an empty wave error, got %v", err)
+	}
+	if err := validateWaves([][]string{{"api"}, {"worker", "api"}}); err == nil || !strings.Contains(err.Error(), "already in wave 0") {
+		t.Fatalf("expected a duplicate error, got %v", err)
+	}
+}
+
+func TestUpdateWave(t *testing.T) {
+	origInspect, origUpdate, origStates := dockerServiceInspect, dockerServiceUpdateSpec, dockerServiceStates
+	origInterval, origStable := waitPollInterval, waitStablePeriod
+	t.Cleanup(func() {
+		dockerServiceInspect, dockerServiceUpdateSpec, dockerServiceStates = origInspect, origUpdate, origStates
+		waitPollInterval, waitStablePeriod = origInterval, origStable
+	})
+	waitPollInterval, waitStablePeriod = time.Millisecond, 0
+
+	dockerServiceInspect = func(name string) (swarmService, error) {
+		if name != "minipaas_api" {
+			return swarmService{}, &dockerapi.Error{StatusCode: http.StatusNotFound}
+		}
+		return swarmService{Version: dockerapi.Version{Index: 7}, RawSpec: json.RawMessage(liveServiceSpec)}, nil
+	}
+	var updated []string
+	var spec dockerapi.ServiceSpec
+	dockerServiceUpdateSpec = func(service string, version uint64, s any) error {
+		updated = append(updated, service)
+		if version != 7 {
+			t.Fatalf("unexpected version %d", version)
+		}
+		_ = json.Unmarshal(s.(json.RawMessage), &spec)
+		return nil
+	}
+	running := task(dockerapi.TaskStateRunning, dockerapi.TaskStateRunning, "", time.Now())
+	dockerServiceStates = func(f dockerapi.Filters) ([]stackServiceState, error) {
+		return []stackServiceState{
+			replicatedState("minipaas_api", 1, "", running),
+			// services outside the wave are not waited for
+			replicatedState("minipaas_worker", 1, dockerapi.UpdateStatePaused),
+		}, nil
+	}
+
+	value := "2"
+	deployment := &types.Project{Services: types.Services{
+		"api":    {Name: "api", Image: "registry:5000/api:1.1.0", Environment: types.MappingWithEquals{"WORKERS": &value}},
+		"emails": {Name: "emails", Image: "registry:5000/emails:1.1.0"},
+	}}
+	if err := updateWave("minipaas", deployment, []string{"api", "emails"}, time.Second); err != nil {
+		t.Fatalf("updateWave: %v", err)
+	}
+	// emails is not running yet and is left to the stack deploy
+	if len(updated) != 1 || updated[0] != "minipaas_api" {
+		t.Fatalf("unexpected updates %v", updated)
+	}
+	container := spec.TaskTemplate.ContainerSpec
+	if container.Image != "registry:5000/api:1.1.0" || len(container.Env) != 1 || container.Env[0] != "WORKERS=2" {
+		t.Fatalf("expected the whole compose spec applied: %#v", container)
+	}
+	// the wave waits on the stack filter, so the service must stay in it
+	if spec.Labels["com.docker.stack.namespace"] != "minipaas" {
+		t.Fatalf("stack labels lost: %v", spec.Labels)
+	}
+}
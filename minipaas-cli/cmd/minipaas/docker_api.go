@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// withDockerClient runs fn with a Docker API client for the daemon selected by
+// setApiEnvVars (DOCKER_HOST, DOCKER_CERT_PATH, DOCKER_TLS_VERIFY).
+func withDockerClient(fn func(ctx context.Context, c *dockerapi.Client) error) error {
+	c, err := dockerapi.NewFromEnv()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return fn(context.Background(), c)
+}
@@ -1,49 +1,412 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"maps"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
-func buildCommandFromService(svc types.ServiceConfig) []string {
-	cmd := []string{"docker", "build", "--network", "host"}
+// buildContext returns the build context, defaulting to the current
+// directory.
+func buildContext(b *types.BuildConfig) string {
+	if b == nil || b.Context == "" {
+		return "."
+	}
+	return b.Context
+}
+
+// commonBuildFlags maps the build fields understood by both `docker build`
+// and `docker buildx build`.
+func commonBuildFlags(svc types.ServiceConfig) []string {
+	var flags []string
 
 	// If an image name is specified, use it to tag the built image.
 	if svc.Image != "" {
-		cmd = append(cmd, "-t", svc.Image)
+		flags = append(flags, "-t", svc.Image)
+	}
+	if svc.Build == nil {
+		return flags
+	}
+	b := svc.Build
+
+	for _, tag := range b.Tags {
+		flags = append(flags, "-t", tag)
+	}
+	if b.Dockerfile != "" {
+		flags = append(flags, "-f", filepath.Join(buildContext(b), b.Dockerfile))
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.Args)) {
+		value := ""
+		if b.Args[key] != nil {
+			value = *b.Args[key]
+		}
+		flags = append(flags, "--build-arg", fmt.Sprintf("%s=%s", key, value))
+	}
+	if b.Target != "" {
+		flags = append(flags, "--target", b.Target)
+	}
+	for _, from := range b.CacheFrom {
+		flags = append(flags, "--cache-from", from)
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.Labels)) {
+		flags = append(flags, "--label", fmt.Sprintf("%s=%s", key, b.Labels[key]))
+	}
+	if b.Network != "" {
+		flags = append(flags, "--network", b.Network)
+	}
+	hosts := b.ExtraHosts.AsList(":")
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		flags = append(flags, "--add-host", host)
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.Ulimits)) {
+		flags = append(flags, "--ulimit", name+"="+ulimitValue(b.Ulimits[name]))
+	}
+	if b.ShmSize > 0 {
+		flags = append(flags, "--shm-size", strconv.FormatInt(int64(b.ShmSize), 10))
+	}
+	if b.NoCache {
+		flags = append(flags, "--no-cache")
+	}
+	if b.Pull {
+		flags = append(flags, "--pull")
+	}
+	return flags
+}
+
+func ulimitValue(u *types.UlimitsConfig) string {
+	if u == nil {
+		return "0"
+	}
+	if u.Single != 0 {
+		return strconv.Itoa(u.Single)
+	}
+	return fmt.Sprintf("%d:%d", u.Soft, u.Hard)
+}
+
+// buildCommandFromService is the `docker build` fallback used when buildx is
+// not available. Services setting fields it cannot express, as reported by
+// classicBuildIgnoredFields, are rejected by buildTargets.
+func buildCommandFromService(svc types.ServiceConfig) []string {
+	cmd := append([]string{"docker", "build"}, commonBuildFlags(svc)...)
+	if svc.Build != nil && len(svc.Build.Platforms) == 1 {
+		cmd = append(cmd, "--platform", svc.Build.Platforms[0])
 	}
+	return append(cmd, buildContext(svc.Build))
+}
 
-	// If the service has build configuration, process it.
-	if svc.Build != nil {
-		context := svc.Build.Context
-		if context == "" {
-			context = "."
+// classicBuildIgnoredFields lists the build fields set on b that `docker
+// build` cannot honor.
+func classicBuildIgnoredFields(b *types.BuildConfig) []string {
+	if b == nil {
+		return nil
+	}
+	var ignored []string
+	add := func(set bool, field string) {
+		if set {
+			ignored = append(ignored, field)
 		}
+	}
+	add(len(b.CacheTo) > 0, "cache_to")
+	add(len(b.Platforms) > 1, "platforms")
+	add(len(b.Secrets) > 0, "secrets")
+	add(len(b.SSH) > 0, "ssh")
+	add(len(b.AdditionalContexts) > 0, "additional_contexts")
+	add(b.Provenance != "", "provenance")
+	add(b.SBOM != "", "sbom")
+	add(len(b.Entitlements) > 0 || b.Privileged, "entitlements")
+	add(b.DockerfileInline != "", "dockerfile_inline")
+	return ignored
+}
 
-		// Specify the Dockerfile if provided.
-		if svc.Build.Dockerfile != "" {
-			cmd = append(cmd, "-f", filepath.Join(context, svc.Build.Dockerfile))
+// buildxCommandFromService maps the compose build spec onto `docker buildx
+// build`. Build secrets are resolved against the project secrets, which must
+// use file or environment. Single platform images are loaded into the local
+// image store for the push phase; multi-platform images can only be pushed by
+// buildx itself, so they require push.
+func buildxCommandFromService(svc types.ServiceConfig, secrets types.Secrets, push bool) ([]string, error) {
+	cmd := append([]string{"docker", "buildx", "build"}, commonBuildFlags(svc)...)
+	b := svc.Build
+	if b == nil {
+		return append(cmd, "--load", buildContext(b)), nil
+	}
+	if b.DockerfileInline != "" {
+		return nil, fmt.Errorf("dockerfile_inline is not supported, use dockerfile")
+	}
+	if len(b.Platforms) > 1 && !push {
+		// buildx cannot load a multi-platform image into the local image
+		// store, so the build would leave nothing to push or run.
+		return nil, fmt.Errorf("building for %d platforms requires --push", len(b.Platforms))
+	}
+
+	for _, to := range b.CacheTo {
+		cmd = append(cmd, "--cache-to", to)
+	}
+	if len(b.Platforms) > 0 {
+		cmd = append(cmd, "--platform", strings.Join(b.Platforms, ","))
+	}
+	for _, ref := range b.Secrets {
+		opt, err := buildSecretOption(ref, secrets)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, "--secret", opt)
+	}
+	for _, key := range b.SSH {
+		if key.Path == "" {
+			cmd = append(cmd, "--ssh", key.ID)
+		} else {
+			cmd = append(cmd, "--ssh", key.ID+"="+key.Path)
 		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.AdditionalContexts)) {
+		cmd = append(cmd, "--build-context", name+"="+b.AdditionalContexts[name])
+	}
+	if b.Provenance != "" {
+		cmd = append(cmd, "--provenance", b.Provenance)
+	}
+	if b.SBOM != "" {
+		cmd = append(cmd, "--sbom", b.SBOM)
+	}
+	for _, entitlement := range b.Entitlements {
+		cmd = append(cmd, "--allow", entitlement)
+	}
+	if b.Privileged {
+		cmd = append(cmd, "--allow", "security.insecure")
+	}
 
-		// Append build arguments if any.f
-		if svc.Build.Args != nil {
-			for key, valPtr := range svc.Build.Args {
-				if valPtr != nil {
-					cmd = append(cmd, "--build-arg", fmt.Sprintf("%s=%s", key, *valPtr))
-				} else {
-					cmd = append(cmd, "--build-arg", fmt.Sprintf("%s=", key))
+	if len(b.Platforms) > 1 {
+		cmd = append(cmd, "--push")
+	} else {
+		cmd = append(cmd, "--load")
+	}
+	return append(cmd, buildContext(b)), nil
+}
+
+// buildSecretOption renders a build secret for --secret. As in compose, the
+// id is the target when set, the source otherwise.
+func buildSecretOption(ref types.ServiceSecretConfig, secrets types.Secrets) (string, error) {
+	def, ok := secrets[ref.Source]
+	if !ok {
+		return "", fmt.Errorf("build secret %s is not declared in secrets", ref.Source)
+	}
+	id := ref.Source
+	if ref.Target != "" {
+		id = ref.Target
+	}
+	switch {
+	case def.Environment != "":
+		return fmt.Sprintf("id=%s,env=%s", id, def.Environment), nil
+	case def.File != "":
+		return fmt.Sprintf("id=%s,src=%s", id, def.File), nil
+	}
+	return "", fmt.Errorf("build secret %s must set file or environment", ref.Source)
+}
+
+// Make external calls overridable for tests
+var dockerImageBuild = func(cmd []string, out io.Writer) error {
+	if dryRun {
+		printDryRunCommand(cmd)
+		return nil
+	}
+	process := exec.Command(cmd[0], cmd[1:]...)
+	process.Stdout = out
+	process.Stderr = out
+	return process.Run()
+}
+
+var dockerBuildxAvailable = func() bool {
+	return exec.Command("docker", "buildx", "version").Run() == nil
+}
+
+// buildTarget is one image build shared by every service with the same image
+// and build context.
+type buildTarget struct {
+	Services []string
+	Image    string
+	Command  []string
+	// PushedByBuild is set for multi-platform images that buildx pushes
+	// itself, as they cannot be loaded for a separate push.
+	PushedByBuild bool
+}
+
+func (t buildTarget) label() string {
+	return strings.Join(t.Services, ",")
+}
+
+// applyEnvBuildArgs gives the build args declared without a value the value
+// the env block of minipaas.yaml sets for them, if any.
+func applyEnvBuildArgs(project *types.Project, vars map[string]string) {
+	for _, svc := range project.Services {
+		if svc.Build == nil {
+			continue
+		}
+		for key, value := range svc.Build.Args {
+			if v, ok := vars[key]; ok && value == nil {
+				svc.Build.Args[key] = &v
+			}
+		}
+	}
+}
+
+// buildTargets returns the builds needed for the services, in service name
+// order, building once for services that share image and context. With
+// buildx unset, builds fall back to `docker build`, and services using
+// fields it cannot honor are rejected rather than built differently.
+func buildTargets(project *types.Project, buildx bool, push bool) ([]buildTarget, error) {
+	var targets []buildTarget
+	index := map[string]int{}
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		svc := project.Services[name]
+		if svc.Build == nil {
+			continue
+		}
+		key := svc.Image + "\x00" + svc.Build.Context
+		if i, ok := index[key]; ok && svc.Image != "" {
+			targets[i].Services = append(targets[i].Services, name)
+			continue
+		}
+
+		target := buildTarget{Services: []string{name}, Image: svc.Image}
+		if buildx {
+			cmd, err := buildxCommandFromService(svc, project.Secrets, push)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			target.Command = cmd
+			target.PushedByBuild = push && len(svc.Build.Platforms) > 1
+		} else {
+			if ignored := classicBuildIgnoredFields(svc.Build); len(ignored) > 0 {
+				return nil, fmt.Errorf("%s: %s require docker buildx, which is not available", name, strings.Join(ignored, ", "))
+			}
+			target.Command = buildCommandFromService(svc)
+		}
+		index[key] = len(targets)
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+type buildResult struct {
+	Target buildTarget
+	Err    error
+}
+
+// runBuilds runs the builds with at most parallel at a time. Output lines are
+// prefixed with [service] and streamed as they come; with quiet, they are
+// buffered and only the output of failed builds is printed.
+func runBuilds(targets []buildTarget, parallel int, quiet, verbose bool, out io.Writer) []buildResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	var mu sync.Mutex
+	results := make([]buildResult, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			prefix := "[" + target.label() + "] "
+			var buffered bytes.Buffer
+			var w *prefixWriter
+			if quiet {
+				w = newPrefixWriter(&buffered, nil, prefix)
+			} else {
+				w = newPrefixWriter(out, &mu, prefix)
+			}
+			if verbose {
+				w.Write([]byte(fmt.Sprintf("🔹 Running: %v\n", target.Command)))
+			}
+			err := dockerImageBuild(target.Command, w)
+			w.Flush()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if quiet {
+					out.Write(buffered.Bytes())
 				}
+				fmt.Fprintf(out, "❌ %s: %v\n", target.label(), err)
+			} else {
+				fmt.Fprintf(out, "✅ %s: %s\n", target.label(), target.Image)
 			}
+			results[i] = buildResult{Target: target, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// printBuildFailures renders one row per failed service.
+func printBuildFailures(w io.Writer, results []buildResult) (int, error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tIMAGE\tERROR")
+	failed := 0
+	for _, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		for _, svc := range r.Target.Services {
+			fmt.Fprintf(tw, "%s\t%s\t%v\n", svc, r.Target.Image, r.Err)
+			failed++
 		}
+	}
+	return failed, tw.Flush()
+}
 
-		// Use the provided build context. If empty, default to current directory.
-		cmd = append(cmd, svc.Build.Context)
-	} else {
-		// If no build configuration is provided, default to current directory.
-		cmd = append(cmd, ".")
+// prefixWriter writes each complete line with a prefix. When mu is set, it
+// is held per line so concurrent builds do not interleave mid-line.
+type prefixWriter struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func newPrefixWriter(out io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, mu: mu, prefix: prefix}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:idx+1])
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+// Flush writes a trailing line without newline, if any.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
 	}
+}
 
-	return cmd
+func (w *prefixWriter) writeLine(line []byte) {
+	if w.mu != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	w.out.Write(append([]byte(w.prefix), line...))
 }
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/compose-spec/compose-go/v2/types"
 )
@@ -49,3 +57,201 @@ func TestBuildCommandFromService(t *testing.T) {
 		t.Fatalf("missing build args: %#v", cmd)
 	}
 }
+
+func TestApplyEnvBuildArgs(t *testing.T) {
+	build := &types.BuildConfig{Context: "./app", Args: types.MappingWithEquals{
+		"NPM_TOKEN": nil,
+		"MODE":      strptr("prod"),
+		"UNSET":     nil,
+	}}
+	project := &types.Project{Services: types.Services{"api": {Name: "api", Image: "api", Build: build}}}
+
+	applyEnvBuildArgs(project, map[string]string{"NPM_TOKEN": "secret", "MODE": "dev"})
+	cmd := buildCommandFromService(project.Services["api"])
+	want := []string{"docker", "build", "-t", "api", "--build-arg", "MODE=prod", "--build-arg", "NPM_TOKEN=secret", "--build-arg", "UNSET=", "./app"}
+	if !reflect.DeepEqual(cmd, want) {
+		t.Fatalf("command mismatch\n got:%#v\nwant:%#v", cmd, want)
+	}
+}
+
+func TestBuildTargets_DedupesSharedImage(t *testing.T) {
+	build := &types.BuildConfig{Context: "./app"}
+	services := types.Services{
+		"api":      {Name: "api", Image: "registry:5000/app:1.0.0", Build: build},
+		"worker":   {Name: "worker", Image: "registry:5000/app:1.0.0", Build: build},
+		"frontend": {Name: "frontend", Image: "registry:5000/web:1.0.0", Build: &types.BuildConfig{Context: "./web"}},
+		"postgres": {Name: "postgres", Image: "postgres:16"},
+	}
+
+	targets, err := buildTargets(&types.Project{Services: services}, true, false)
+	if err != nil {
+		t.Fatalf("buildTargets: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 builds, got %#v", targets)
+	}
+	if targets[0].label() != "api,worker" || targets[1].label() != "frontend" {
+		t.Fatalf("targets mismatch: %s / %s", targets[0].label(), targets[1].label())
+	}
+}
+
+func TestBuildxCommandFromService(t *testing.T) {
+	svc := types.ServiceConfig{
+		Image: "registry:5000/api:1.0.0",
+		Build: &types.BuildConfig{
+			Context:    "./api",
+			Dockerfile: "Dockerfile",
+			Target:     "runtime",
+			CacheFrom:  types.StringList{"type=registry,ref=registry:5000/api:cache"},
+			CacheTo:    types.StringList{"type=registry,ref=registry:5000/api:cache,mode=max"},
+			Platforms:  types.StringList{"linux/amd64"},
+			Secrets:    []types.ServiceSecretConfig{{Source: "npm_token"}, {Source: "gh", Target: "github_token"}},
+			SSH:        types.SSHConfig{{ID: "default"}},
+			Labels:     types.Labels{"org.opencontainers.image.source": "repo"},
+			Network:    "host",
+			ExtraHosts: types.HostsList{"db": {"10.0.0.5"}},
+		},
+	}
+	secrets := types.Secrets{
+		"npm_token": {File: "./secrets/npm_token"},
+		"gh":        {Environment: "GITHUB_TOKEN"},
+	}
+
+	cmd, err := buildxCommandFromService(svc, secrets, false)
+	if err != nil {
+		t.Fatalf("buildxCommandFromService: %v", err)
+	}
+	want := []string{
+		"docker", "buildx", "build",
+		"-t", "registry:5000/api:1.0.0",
+		"-f", filepath.Join("./api", "Dockerfile"),
+		"--target", "runtime",
+		"--cache-from", "type=registry,ref=registry:5000/api:cache",
+		"--label", "org.opencontainers.image.source=repo",
+		"--network", "host",
+		"--add-host", "db:10.0.0.5",
+		"--cache-to", "type=registry,ref=registry:5000/api:cache,mode=max",
+		"--platform", "linux/amd64",
+		"--secret", "id=npm_token,src=./secrets/npm_token",
+		"--secret", "id=github_token,env=GITHUB_TOKEN",
+		"--ssh", "default",
+		"--load",
+		"./api",
+	}
+	if !reflect.DeepEqual(cmd, want) {
+		t.Fatalf("command mismatch\n got:%#v\nwant:%#v", cmd, want)
+	}
+
+	// multi-platform images are pushed by buildx
+	svc.Build.Platforms = types.StringList{"linux/amd64", "linux/arm64"}
+	cmd, _ = buildxCommandFromService(svc, secrets, true)
+	if cmd[len(cmd)-2] != "--push" {
+		t.Fatalf("expected --push for multi-platform build: %#v", cmd)
+	}
+	if _, err = buildxCommandFromService(svc, secrets, false); err == nil || !strings.Contains(err.Error(), "requires --push") {
+		t.Fatalf("expected multi-platform build without push to be rejected, got %v", err)
+	}
+
+	svc.Build.Secrets = []types.ServiceSecretConfig{{Source: "missing"}}
+	if _, err = buildxCommandFromService(svc, secrets, false); err == nil {
+		t.Fatalf("expected error for undeclared build secret")
+	}
+}
+
+func TestBuildCommandFromService_Fallback(t *testing.T) {
+	b := &types.BuildConfig{Context: "./api", Target: "runtime", CacheTo: types.StringList{"type=inline"}, Platforms: types.StringList{"linux/arm64"}}
+	cmd := buildCommandFromService(types.ServiceConfig{Image: "api", Build: b})
+	want := []string{"docker", "build", "-t", "api", "--target", "runtime", "--platform", "linux/arm64", "./api"}
+	if !reflect.DeepEqual(cmd, want) {
+		t.Fatalf("command mismatch\n got:%#v\nwant:%#v", cmd, want)
+	}
+	if ignored := classicBuildIgnoredFields(b); !reflect.DeepEqual(ignored, []string{"cache_to"}) {
+		t.Fatalf("ignored fields mismatch: %v", ignored)
+	}
+
+	project := &types.Project{Services: types.Services{"api": {Name: "api", Image: "api", Build: b}}}
+	if _, err := buildTargets(project, false, false); err == nil || !strings.Contains(err.Error(), "cache_to require docker buildx") {
+		t.Fatalf("expected the fallback to reject cache_to, got %v", err)
+	}
+}
+
+func TestRunBuilds_ParallelAndFailures(t *testing.T) {
+	orig := dockerImageBuild
+	t.Cleanup(func() { dockerImageBuild = orig })
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	dockerImageBuild = func(cmd []string, out io.Writer) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		fmt.Fprintf(out, "step 1\nstep 2")
+		if cmd[len(cmd)-1] == "./broken" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	targets := []buildTarget{
+		{Services: []string{"a"}, Image: "a:1", Command: []string{"docker", "build", "./a"}},
+		{Services: []string{"b"}, Image: "b:1", Command: []string{"docker", "build", "./broken"}},
+		{Services: []string{"c", "d"}, Image: "c:1", Command: []string{"docker", "build", "./c"}},
+	}
+	var out bytes.Buffer
+	results := runBuilds(targets, 2, true, false, &out)
+
+	if maxRunning > 2 {
+		t.Fatalf("more than 2 builds ran at once: %d", maxRunning)
+	}
+	if results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Fatalf("results mismatch: %#v", results)
+	}
+	// only the failed build's output is shown when quiet
+	if !strings.Contains(out.String(), "[b] step 1\n[b] step 2\n") || strings.Contains(out.String(), "[a] step") {
+		t.Fatalf("output mismatch:\n%s", out.String())
+	}
+
+	var table bytes.Buffer
+	failed, err := printBuildFailures(&table, results)
+	if err != nil || failed != 1 || !strings.Contains(table.String(), "exit status 1") {
+		t.Fatalf("failure table mismatch (%d, %v):\n%s", failed, err, table.String())
+	}
+}
+
+func TestRunBuilds_StreamsByDefault(t *testing.T) {
+	orig := dockerImageBuild
+	t.Cleanup(func() { dockerImageBuild = orig })
+	dockerImageBuild = func(cmd []string, out io.Writer) error {
+		fmt.Fprintf(out, "step 1\n")
+		return nil
+	}
+
+	targets := []buildTarget{
+		{Services: []string{"a"}, Image: "a:1", Command: []string{"docker", "build", "./a"}},
+		{Services: []string{"b"}, Image: "b:1", Command: []string{"docker", "build", "./b"}},
+	}
+	var out bytes.Buffer
+	runBuilds(targets, 2, false, false, &out)
+	if !strings.Contains(out.String(), "[a] step 1\n") || !strings.Contains(out.String(), "[b] step 1\n") {
+		t.Fatalf("expected streamed output of every build:\n%s", out.String())
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newPrefixWriter(&out, nil, "[api] ")
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\nthree"))
+	w.Flush()
+	if out.String() != "[api] one\n[api] two\n[api] three\n" {
+		t.Fatalf("prefixed output mismatch: %q", out.String())
+	}
+}
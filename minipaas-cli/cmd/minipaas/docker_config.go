@@ -1,37 +1,111 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"os/exec"
+	"slices"
+	"sort"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
 )
 
 // Make external calls overridable for tests
 var dockerConfigInspect = func(name string) error {
-	return exec.Command("docker", "config", "inspect", name).Run()
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		_, err := c.ConfigInspect(ctx, name)
+		return err
+	})
+}
+
+var dockerConfigCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "config", "create", name, "-"})
+		return nil
+	}
+	if verbose {
+		fmt.Printf("🔹 Creating config: %s\n", name)
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		_, err := c.ConfigCreate(ctx, dockerapi.ConfigSpec{Name: name, Data: content, Labels: labels})
+		return err
+	})
+}
+
+var dockerConfigList = func(filters dockerapi.Filters) ([]string, error) {
+	var names []string
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		objects, err := c.ConfigList(ctx, filters)
+		for _, o := range objects {
+			names = append(names, o.Spec.Name)
+		}
+		return err
+	})
+	return names, err
 }
 
-var dockerConfigCreate = func(name string, content []byte, verbose bool) error {
-	return runCommandWithInput([]string{"docker", "config", "create", name, "-"}, content, verbose)
+// dockerConfigIDs maps the given config names to their IDs; names that do not
+// exist are left out.
+var dockerConfigIDs = func(names []string) (map[string]string, error) {
+	ids := map[string]string{}
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		objects, err := c.ConfigList(ctx, dockerapi.Filters{"name": names})
+		for _, o := range objects {
+			// the name filter matches prefixes
+			if slices.Contains(names, o.Spec.Name) {
+				ids[o.Spec.Name] = o.ID
+			}
+		}
+		return err
+	})
+	return ids, err
+}
+
+var dockerConfigRemove = func(name string, verbose bool) error {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "config", "rm", name})
+		return nil
+	}
+	if verbose {
+		fmt.Printf("🔹 Removing config: %s\n", name)
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		return c.ConfigRemove(ctx, name)
+	})
 }
 
 func configExists(name string) bool {
 	return dockerConfigInspect(name) == nil
 }
 
-func configCreate(baseName string, config []byte, verbose bool) (string, error) {
+// configCreate creates the hashed version of baseName for content, labelled
+// with the stack it is created for.
+func configCreate(stack, baseName string, config []byte, verbose bool) (string, error) {
 	hash := sha256.Sum256(config)
-	hashPrefix := hex.EncodeToString(hash[:])[:8]
+	hashPrefix := hex.EncodeToString(hash[:])[:hashSuffixLen]
 
 	configName := fmt.Sprintf("%s.%s", baseName, hashPrefix)
 
-	return configName, configCreateLiteral(configName, config, verbose)
+	return configName, configCreateLiteral(stack, configName, config, verbose)
 }
 
-func configCreateLiteral(configName string, config []byte, verbose bool) error {
+func configCreateLiteral(stack, configName string, config []byte, verbose bool) error {
 	if configExists(configName) {
 		return nil
 	}
-	return dockerConfigCreate(configName, config, verbose)
+	return dockerConfigCreate(configName, config, stackObjectLabels(stack), verbose)
+}
+
+// configVersions returns the Swarm configs of stack that are baseName or one
+// of its hashed versions, sorted by name: the ones labelled for stack and the
+// unlabelled ones referenced by its compose files.
+func configVersions(stack, baseName string, referenced map[string]bool) ([]string, error) {
+	names, err := stackObjects(dockerConfigList, stack, func(name string) bool { return referenced[name] })
+	if err != nil {
+		return nil, err
+	}
+	versions := filterObjectVersions(names, baseName)
+	sort.Strings(versions)
+	return versions, nil
 }
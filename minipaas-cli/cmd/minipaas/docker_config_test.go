@@ -12,7 +12,7 @@ func TestConfigCreate_NameAndLiteralCall(t *testing.T) {
 	var gotName string
 	var gotBytes []byte
 	dockerConfigInspect = func(name string) error { return assertErr } // signal not exists
-	dockerConfigCreate = func(name string, content []byte, verbose bool) error {
+	dockerConfigCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
 		called = true
 		gotName = name
 		gotBytes = append([]byte(nil), content...)
@@ -20,11 +20,11 @@ func TestConfigCreate_NameAndLiteralCall(t *testing.T) {
 	}
 	t.Cleanup(func() {
 		dockerConfigInspect = func(n string) error { return nil }
-		dockerConfigCreate = func(name string, content []byte, verbose bool) error { return nil }
+		dockerConfigCreate = func(name string, content []byte, labels map[string]string, verbose bool) error { return nil }
 	})
 
 	content := []byte("hello")
-	name, err := configCreate("app.conf", content, false)
+	name, err := configCreate("minipaas", "app.conf", content, false)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -49,16 +49,37 @@ func (e *fakeError) Error() string { return "assert" }
 func TestConfigCreateLiteral_ExistsSkips(t *testing.T) {
 	invoked := false
 	dockerConfigInspect = func(name string) error { return nil } // exists
-	dockerConfigCreate = func(name string, content []byte, verbose bool) error { invoked = true; return nil }
+	dockerConfigCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
+		invoked = true
+		return nil
+	}
 	t.Cleanup(func() {
 		dockerConfigInspect = func(n string) error { return nil }
-		dockerConfigCreate = func(name string, content []byte, verbose bool) error { return nil }
+		dockerConfigCreate = func(name string, content []byte, labels map[string]string, verbose bool) error { return nil }
 	})
 
-	if err := configCreateLiteral("cfg", []byte("x"), false); err != nil {
+	if err := configCreateLiteral("minipaas", "cfg", []byte("x"), false); err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 	if invoked {
 		t.Fatalf("runner should not be invoked when exists=true")
 	}
 }
+
+func TestConfigVersions(t *testing.T) {
+	orig := dockerConfigList
+	t.Cleanup(func() { dockerConfigList = orig })
+	dockerConfigList = fakeObjectList(map[string]string{
+		"app.conf.267da420": "minipaas",
+		"app.json.267da420": "minipaas",
+		"app.conf.89abcdef": "shop", // same name, owned by another stack
+	})
+
+	got, err := configVersions("minipaas", "app.conf", nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != 1 || got[0] != "app.conf.267da420" {
+		t.Fatalf("versions mismatch: %#v", got)
+	}
+}
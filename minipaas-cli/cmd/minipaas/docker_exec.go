@@ -1,36 +1,77 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"io"
+	"os"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
 )
 
-// indirection for testability
-var _runCommand = runCommand
-var _runCommandOutput = runCommandOutput
+// Make external calls overridable for tests
+var dockerExec = func(containerID string, cmd []string, stdout, stderr io.Writer) (int, error) {
+	exitCode := -1
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) (err error) {
+		exitCode, err = c.Exec(ctx, containerID, cmd, nil, stdout, stderr)
+		return err
+	})
+	return exitCode, err
+}
+
+var dockerServiceContainers = func(serviceName string) ([]string, error) {
+	var ids []string
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		containers, err := c.ContainerList(ctx, false, dockerapi.Filters{
+			"label": {"com.docker.swarm.service.name=" + serviceName},
+		})
+		for _, container := range containers {
+			ids = append(ids, container.ID)
+		}
+		return err
+	})
+	return ids, err
+}
+
+func runContainerExec(containerID string, args []string, stdout, stderr io.Writer) error {
+	if dryRun {
+		printDryRunCommand(append([]string{"docker", "exec", containerID}, args...))
+		return nil
+	}
+	exitCode, err := dockerExec(containerID, args, stdout, stderr)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("%v exited with status %d", args, exitCode)
+	}
+	return nil
+}
 
 func dockerContainerExec(containerID string, args []string, verbose bool) error {
-	allArgs := append([]string{"docker", "exec", "-i", containerID}, args...)
-	return _runCommand(allArgs, verbose)
+	if verbose {
+		fmt.Printf("🔹 Running in %s: %v\n", containerID, args)
+		return runContainerExec(containerID, args, os.Stdout, os.Stderr)
+	}
+	return runContainerExec(containerID, args, nil, nil)
 }
 
 func dockerContainerExecOutput(containerID string, args []string, verbose bool) (string, error) {
-	allArgs := append([]string{"docker", "exec", "-i", containerID}, args...)
-	return _runCommandOutput(allArgs, verbose)
+	if verbose {
+		fmt.Printf("🔹 Running in %s: %v\n", containerID, args)
+	}
+	var output bytes.Buffer
+	err := runContainerExec(containerID, args, &output, &output)
+	return output.String(), err
 }
 
 func getContainerID(serviceName string) (string, error) {
-	cmd := []string{
-		"docker", "ps",
-		"--filter", "label=com.docker.swarm.service.name=" + serviceName,
-		"--format", "{{.ID}}",
-	}
-	output, err := _runCommandOutput(cmd, false)
+	ids, err := dockerServiceContainers(serviceName)
 	if err != nil {
-		return "", fmt.Errorf("docker ps command failed: %v", err)
+		return "", fmt.Errorf("listing containers failed: %v", err)
 	}
-	ids := strings.Fields(output)
 	if len(ids) == 0 {
 		return "", errors.New("no running container found for service " + serviceName)
 	}
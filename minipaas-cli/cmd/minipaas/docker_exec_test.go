@@ -2,73 +2,92 @@ package main
 
 import (
 	"errors"
+	"io"
 	"reflect"
 	"testing"
 )
 
-func TestDockerContainerExec_BuildsArgs(t *testing.T) {
+func TestDockerContainerExec_PassesArgs(t *testing.T) {
+	var gotID string
 	var got []string
-	_runCommand = func(cmd []string, verbose bool) error {
+	orig := dockerExec
+	t.Cleanup(func() { dockerExec = orig })
+	dockerExec = func(containerID string, cmd []string, stdout, stderr io.Writer) (int, error) {
+		gotID = containerID
 		got = append([]string{}, cmd...)
-		return nil
+		return 0, nil
 	}
-	t.Cleanup(func() { _runCommand = runCommand })
 
 	err := dockerContainerExec("abc123", []string{"sh", "-c", "echo ok"}, false)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
-	want := []string{"docker", "exec", "-i", "abc123", "sh", "-c", "echo ok"}
-	if !reflect.DeepEqual(got, want) {
-		t.Fatalf("args mismatch\n got:%#v\nwant:%#v", got, want)
+	want := []string{"sh", "-c", "echo ok"}
+	if gotID != "abc123" || !reflect.DeepEqual(got, want) {
+		t.Fatalf("args mismatch\n got:%s %#v\nwant:abc123 %#v", gotID, got, want)
 	}
 }
 
-func TestDockerContainerExecOutput_BuildsArgs(t *testing.T) {
-	var got []string
-	_runCommandOutput = func(cmd []string, verbose bool) (string, error) {
-		got = append([]string{}, cmd...)
-		return "output", nil
+func TestDockerContainerExec_ExitCode(t *testing.T) {
+	orig := dockerExec
+	t.Cleanup(func() { dockerExec = orig })
+	dockerExec = func(containerID string, cmd []string, stdout, stderr io.Writer) (int, error) {
+		return 2, nil
 	}
-	t.Cleanup(func() { _runCommandOutput = runCommandOutput })
 
-	_, err := dockerContainerExecOutput("cid", []string{"env"}, true)
+	if err := dockerContainerExec("abc123", []string{"false"}, false); err == nil {
+		t.Fatalf("expected error for non-zero exit code")
+	}
+}
+
+func TestDockerContainerExecOutput_CombinesStreams(t *testing.T) {
+	orig := dockerExec
+	t.Cleanup(func() { dockerExec = orig })
+	dockerExec = func(containerID string, cmd []string, stdout, stderr io.Writer) (int, error) {
+		io.WriteString(stdout, "out\n")
+		io.WriteString(stderr, "err\n")
+		return 0, nil
+	}
+
+	out, err := dockerContainerExecOutput("cid", []string{"env"}, true)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
-	want := []string{"docker", "exec", "-i", "cid", "env"}
-	if !reflect.DeepEqual(got, want) {
-		t.Fatalf("args mismatch\n got:%#v\nwant:%#v", got, want)
+	if out != "out\nerr\n" {
+		t.Fatalf("output mismatch: %q", out)
 	}
 }
 
 func TestGetContainerID(t *testing.T) {
-	// success: returns first non-empty id
-	_runCommandOutput = func(cmd []string, verbose bool) (string, error) {
-		return "id1\nid2\n", nil
-	}
-	t.Cleanup(func() { _runCommandOutput = runCommandOutput })
+	orig := dockerServiceContainers
+	t.Cleanup(func() { dockerServiceContainers = orig })
 
+	// success: returns first id
+	var gotService string
+	dockerServiceContainers = func(serviceName string) ([]string, error) {
+		gotService = serviceName
+		return []string{"id1", "id2"}, nil
+	}
 	id, err := getContainerID("svc")
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
-	if id != "id1" {
-		t.Fatalf("id mismatch: %q", id)
+	if id != "id1" || gotService != "svc" {
+		t.Fatalf("id mismatch: %q (service %q)", id, gotService)
 	}
 
 	// no ids
-	_runCommandOutput = func(cmd []string, verbose bool) (string, error) {
-		return "\n", nil
+	dockerServiceContainers = func(serviceName string) ([]string, error) {
+		return nil, nil
 	}
 	_, err = getContainerID("svc")
 	if err == nil {
 		t.Fatalf("expected error when no containers")
 	}
 
-	// command failure
-	_runCommandOutput = func(cmd []string, verbose bool) (string, error) {
-		return "", errors.New("boom")
+	// API failure
+	dockerServiceContainers = func(serviceName string) ([]string, error) {
+		return nil, errors.New("boom")
 	}
 	_, err = getContainerID("svc")
 	if err == nil {
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// objectStackLabel marks the secrets and configs MiniPaaS creates with the
+// stack they were created for, so that pruning one stack leaves the objects
+// of the others alone.
+const objectStackLabel = "minipaas.stack"
+
+func stackObjectLabels(stack string) map[string]string {
+	return map[string]string{objectStackLabel: stack}
+}
+
+// stackObjectFilter selects the secrets and configs created for stack.
+func stackObjectFilter(stack string) dockerapi.Filters {
+	return dockerapi.Filters{"label": {objectStackLabel + "=" + stack}}
+}
+
+// stackObjects returns the secrets or configs of stack from list: the ones
+// labelled for it and the unlabelled ones legacy accepts, since the objects
+// created before the label was introduced carry none. Objects labelled for
+// another stack are never returned.
+func stackObjects(list func(dockerapi.Filters) ([]string, error), stack string, legacy func(name string) bool) ([]string, error) {
+	owned, err := list(stackObjectFilter(stack))
+	if err != nil {
+		return nil, err
+	}
+	labelled, err := list(dockerapi.Filters{"label": {objectStackLabel}})
+	if err != nil {
+		return nil, err
+	}
+	all, err := list(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range all {
+		if !slices.Contains(labelled, name) && legacy(name) {
+			owned = append(owned, name)
+		}
+	}
+	sort.Strings(owned)
+	return owned, nil
+}
+
+// referencedBaseName accepts the names whose logical name is one of the
+// referenced versions'.
+func referencedBaseName(referenced map[string]bool) func(string) bool {
+	bases := map[string]bool{}
+	for name := range referenced {
+		bases[objectBaseName(name)] = true
+	}
+	return func(name string) bool { return bases[objectBaseName(name)] }
+}
+
+// hashSuffixLen is the length of the content hash that secretCreate and
+// configCreate append to the base name (<base>.<hash>).
+const hashSuffixLen = 8
+
+func isHashSuffix(s string) bool {
+	if len(s) != hashSuffixLen {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+// objectBaseName returns the logical name of a secret/config, stripping the
+// hash suffix when the name is a hashed version.
+func objectBaseName(name string) string {
+	idx := strings.LastIndex(name, ".")
+	if idx == -1 || !isHashSuffix(name[idx+1:]) {
+		return name
+	}
+	return name[:idx]
+}
+
+// objectNameMatches reports whether name is baseName itself or one of its
+// hashed versions.
+func objectNameMatches(name, baseName string) bool {
+	return objectBaseName(name) == baseName
+}
+
+// filterObjectVersions returns the names that match baseName, preserving order.
+func filterObjectVersions(names []string, baseName string) []string {
+	var versions []string
+	for _, name := range names {
+		if objectNameMatches(name, baseName) {
+			versions = append(versions, name)
+		}
+	}
+	return versions
+}
+
+// findOrphanObjects returns the hashed objects in existing that are neither
+// referenced by the compose files nor mounted by a running service. Names
+// without a hash suffix were not created by MiniPaaS and are never returned.
+func findOrphanObjects(existing []string, referenced map[string]bool, mounted map[string][]string) []string {
+	var orphans []string
+	for _, name := range existing {
+		if objectBaseName(name) == name {
+			continue
+		}
+		if referenced[name] || len(mounted[name]) > 0 {
+			continue
+		}
+		orphans = append(orphans, name)
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+const (
+	objectStatusOK           = "ok"
+	objectStatusMissing      = "missing in swarm"
+	objectStatusUnreferenced = "unreferenced"
+)
+
+// objectInventoryEntry is one secret/config version as seen from both the
+// compose files and the cluster.
+type objectInventoryEntry struct {
+	BaseName string
+	Name     string
+	Mounts   []objectMount
+	Status   string
+}
+
+// buildObjectInventory joins the versions referenced by compose with those
+// present in Swarm. Referenced versions missing from inSwarm are reported as
+// missing; versions listed in Swarm are reported as unreferenced when their
+// logical name is known to the compose files but the version itself is not.
+func buildObjectInventory(referenced map[string][]objectMount, inSwarm []string) []objectInventoryEntry {
+	var entries []objectInventoryEntry
+	baseNames := map[string]bool{}
+
+	for name, mounts := range referenced {
+		status := objectStatusOK
+		if !slices.Contains(inSwarm, name) {
+			status = objectStatusMissing
+		}
+		baseNames[objectBaseName(name)] = true
+		entries = append(entries, objectInventoryEntry{
+			BaseName: objectBaseName(name),
+			Name:     name,
+			Mounts:   mounts,
+			Status:   status,
+		})
+	}
+
+	for _, name := range inSwarm {
+		if _, ok := referenced[name]; ok || !baseNames[objectBaseName(name)] {
+			continue
+		}
+		entries = append(entries, objectInventoryEntry{
+			BaseName: objectBaseName(name),
+			Name:     name,
+			Status:   objectStatusUnreferenced,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].BaseName != entries[j].BaseName {
+			return entries[i].BaseName < entries[j].BaseName
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// countObjectStatus returns how many entries have the given status.
+func countObjectStatus(entries []objectInventoryEntry, status string) int {
+	count := 0
+	for _, e := range entries {
+		if e.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// printObjectInventory renders the inventory as a table, one row per mount.
+func printObjectInventory(w io.Writer, entries []objectInventoryEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tSERVICE\tTARGET\tSTATUS")
+	for _, e := range entries {
+		if len(e.Mounts) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t%s\n", e.BaseName, e.Name, e.Status)
+			continue
+		}
+		for _, m := range e.Mounts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.BaseName, e.Name, m.Service, m.Target, e.Status)
+		}
+	}
+	return tw.Flush()
+}
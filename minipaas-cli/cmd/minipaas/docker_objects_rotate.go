@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// objectRotation holds the secret or config operations behind
+// `secret rotate` and `config rotate`. R is the compose mount type.
+type objectRotation[R any] struct {
+	Kind   string
+	Create func(stack, baseName string, content []byte, verbose bool) (string, error)
+	// Swap points the compose mounts of previous versions at the new one and
+	// returns the swapped mounts per service and the previous versions.
+	Swap func(project *types.Project, baseName, newName string) (map[string][]R, []string)
+	// Mounted returns the versions a live service mounts.
+	Mounted func(svc swarmService) []string
+	Flags   func(remove []string, add []R) []string
+	Remove  func(name string, verbose bool) error
+}
+
+var secretRotation = objectRotation[types.ServiceSecretConfig]{
+	Kind:   "secret",
+	Create: secretCreate,
+	Swap:   rotateComposeSecret,
+	Mounted: func(svc swarmService) []string {
+		var names []string
+		for _, ref := range svc.Spec.TaskTemplate.ContainerSpec.Secrets {
+			names = append(names, ref.SecretName)
+		}
+		return names
+	},
+	Flags: secretSwapFlags,
+	// Looked up on each call, so that tests can swap the hook.
+	Remove: func(name string, verbose bool) error { return dockerSecretRemove(name, verbose) },
+}
+
+var configRotation = objectRotation[types.ServiceConfigObjConfig]{
+	Kind:   "config",
+	Create: configCreate,
+	Swap:   rotateComposeConfig,
+	Mounted: func(svc swarmService) []string {
+		var names []string
+		for _, ref := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+			names = append(names, ref.ConfigName)
+		}
+		return names
+	},
+	Flags:  configSwapFlags,
+	Remove: func(name string, verbose bool) error { return dockerConfigRemove(name, verbose) },
+}
+
+// objectRotateOptions are the arguments shared by the rotate commands.
+type objectRotateOptions struct {
+	Env       string
+	Name      string
+	File      string
+	Rollout   bool
+	DeleteOld bool
+	Verbose   bool
+}
+
+// rotateObject creates a new version of a secret or config from a file or
+// STDIN, swaps every compose mount of its previous versions in place and,
+// with Rollout, updates only the services mounting it, then removes the
+// previous versions with DeleteOld.
+func rotateObject[R any](ops objectRotation[R], opts objectRotateOptions) {
+	kind := ops.Kind
+	title := strings.ToUpper(kind[:1]) + kind[1:]
+	if opts.DeleteOld && !opts.Rollout {
+		checkErrorPanic(fmt.Errorf("--delete-old requires --rollout"), "❌ Invalid arguments")
+	}
+
+	cfg, configFile, err := loadConfig(opts.Env)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error loading configuration file: %s", configFile))
+	setApiEnvVars(opts.Env, cfg, opts.Verbose)
+
+	var content []byte
+	if opts.File != "" {
+		content, err = os.ReadFile(opts.File)
+		checkErrorPanic(err, fmt.Sprintf("❌ Failed reading file: %s", opts.File))
+	} else {
+		content, err = io.ReadAll(os.Stdin)
+		checkErrorPanic(err, "❌ Failed reading from STDIN")
+	}
+
+	if len(content) == 0 {
+		log.Printf("⚠️ Input is empty, skipping.")
+		return
+	}
+
+	stack := cfg.StackName()
+	baseName := objectBaseName(opts.Name)
+	newName, err := ops.Create(stack, baseName, content, opts.Verbose)
+	checkErrorPanic(err, fmt.Sprintf("❌ Error creating %s for input: %s", kind, baseName))
+	fmt.Printf("✅ %s created: %s\n", title, newName)
+
+	// Swap every mount of a previous version in place, in every compose file
+	swapped := map[string][]R{}
+	var previous []string
+	for _, file := range composeFilesForEnv(opts.Env, cfg) {
+		project, _, lerr := loadComposeFile(file)
+		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to load compose file: %s", file))
+
+		fileSwapped, old := ops.Swap(project, baseName, newName)
+		if len(fileSwapped) == 0 && len(old) == 0 {
+			continue
+		}
+
+		_, lerr = saveComposeFile(file, project)
+		checkErrorPanic(lerr, fmt.Sprintf("❌ Failed to update compose file: %s", file))
+		fmt.Printf("✅ Updated compose file with %s: %s\n", kind, file)
+
+		for svc, refs := range fileSwapped {
+			swapped[svc] = append(swapped[svc], refs...)
+		}
+		previous = append(previous, old...)
+	}
+
+	if len(swapped) == 0 {
+		log.Printf("⚠️ No service mounts a previous version of %s, nothing to rotate.", baseName)
+		return
+	}
+	if !opts.Rollout {
+		fmt.Println("🔹 Run `deploy rollout` (or re-run with --rollout) to apply the new version")
+		return
+	}
+
+	services, err := dockerServiceList()
+	checkErrorPanic(err, "❌ Failed to list Swarm services")
+
+	failed := 0
+	for _, svcName := range slices.Sorted(maps.Keys(swapped)) {
+		liveName := stackService(stack, svcName)
+		live, ok := findSwarmService(services, liveName)
+		if !ok {
+			log.Printf("⚠️ Service %s is not deployed, skipping.", liveName)
+			continue
+		}
+
+		var remove []string
+		mounted := false
+		for _, name := range ops.Mounted(live) {
+			switch {
+			case name == newName:
+				mounted = true
+			case objectNameMatches(name, baseName):
+				remove = append(remove, name)
+			}
+		}
+		if mounted && len(remove) == 0 {
+			fmt.Printf("✅ %s: already uses %s\n", svcName, newName)
+			continue
+		}
+		add := swapped[svcName]
+		if mounted {
+			add = nil
+		}
+		previous = append(previous, remove...)
+
+		err = dockerServiceUpdate(liveName, ops.Flags(remove, add), opts.Verbose)
+		if err != nil {
+			fmt.Printf("❌ Failed to rotate %s for service %s: %v\n", kind, svcName, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: %s\n", svcName, newName)
+	}
+	if failed > 0 {
+		checkErrorPanic(fmt.Errorf("%d service(s) did not converge, previous versions kept", failed), fmt.Sprintf("❌ Failed to rotate %s", kind))
+	}
+
+	if !opts.DeleteOld {
+		return
+	}
+	slices.Sort(previous)
+	for _, name := range slices.Compact(previous) {
+		if err = ops.Remove(name, opts.Verbose); err != nil {
+			fmt.Printf("❌ Failed to remove %s %s: %v\n", kind, name, err)
+			continue
+		}
+		fmt.Printf("✅ %s removed: %s\n", title, name)
+	}
+}
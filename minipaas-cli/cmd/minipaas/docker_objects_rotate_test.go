@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestObjectRotationMounted(t *testing.T) {
+	var svc swarmService
+	spec := `{"Spec": {"TaskTemplate": {"ContainerSpec": {
+		"Secrets": [{"SecretName": "db.267da420"}],
+		"Configs": [{"ConfigName": "app.conf.0123abcd"}, {"ConfigName": "other"}]
+	}}}}`
+	if err := json.Unmarshal([]byte(spec), &svc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := secretRotation.Mounted(svc); !slices.Equal(got, []string{"db.267da420"}) {
+		t.Fatalf("unexpected secrets: %v", got)
+	}
+	if got := configRotation.Mounted(svc); !slices.Equal(got, []string{"app.conf.0123abcd", "other"}) {
+		t.Fatalf("unexpected configs: %v", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// fakeObjectList lists objects, mapped to their stack label ("" when
+// unlabelled), the way the Engine API applies the label filters.
+func fakeObjectList(objects map[string]string) func(dockerapi.Filters) ([]string, error) {
+	return func(filters dockerapi.Filters) ([]string, error) {
+		var names []string
+		for name, stack := range objects {
+			match := true
+			for _, label := range filters["label"] {
+				key, value, hasValue := strings.Cut(label, "=")
+				match = match && key == objectStackLabel && stack != "" && (!hasValue || value == stack)
+			}
+			if match {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+}
+
+func TestObjectBaseName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"postgres_password.267da420", "postgres_password"},
+		{"postgres_password", "postgres_password"},
+		{"app.conf.0123abcd", "app.conf"},
+		{"app.conf", "app.conf"},
+		{"app.ABCDEF12", "app.ABCDEF12"},
+		{"app.123", "app.123"},
+	}
+	for _, tt := range tests {
+		if got := objectBaseName(tt.in); got != tt.want {
+			t.Fatalf("objectBaseName(%q)=%q want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterObjectVersions(t *testing.T) {
+	names := []string{"db.267da420", "db", "db_user.267da420", "other.0123abcd", "db.0123abcd"}
+	got := filterObjectVersions(names, "db")
+	want := []string{"db.267da420", "db", "db.0123abcd"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("filterObjectVersions mismatch\n got:%#v\nwant:%#v", got, want)
+	}
+}
+
+func TestFindOrphanObjects(t *testing.T) {
+	existing := []string{"db.267da420", "db.0123abcd", "db.aaaaaaaa", "manual_secret", "api_key.bbbbbbbb"}
+	referenced := map[string]bool{"db.267da420": true}
+	mounted := map[string][]string{"db.0123abcd": {"minipaas_worker"}}
+
+	got := findOrphanObjects(existing, referenced, mounted)
+	want := []string{"api_key.bbbbbbbb", "db.aaaaaaaa"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("orphans mismatch\n got:%#v\nwant:%#v", got, want)
+	}
+}
+
+func TestStackObjects(t *testing.T) {
+	list := fakeObjectList(map[string]string{
+		"postgres_password.0123abcd": "shop",
+		"postgres_password.267da420": "",     // created before objects were labelled
+		"postgres_password.89abcdef": "blog", // same name, other stack
+		"unrelated.267da420":         "",
+	})
+	referenced := map[string]bool{"postgres_password.0123abcd": true}
+
+	got, err := stackObjects(list, "shop", referencedBaseName(referenced))
+	if err != nil {
+		t.Fatalf("stackObjects: %v", err)
+	}
+	want := []string{"postgres_password.0123abcd", "postgres_password.267da420"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("objects mismatch\n got:%#v\nwant:%#v", got, want)
+	}
+
+	// the unlabelled stale version is a prune candidate once nothing mounts it
+	orphans := findOrphanObjects(got, referenced, map[string][]string{})
+	if !reflect.DeepEqual(orphans, []string{"postgres_password.267da420"}) {
+		t.Fatalf("orphans mismatch: %#v", orphans)
+	}
+}
+
+func TestBuildObjectInventory(t *testing.T) {
+	referenced := map[string][]objectMount{
+		"db.267da420": {
+			{Service: "api", Target: "db"},
+			{Service: "worker", Target: "db"},
+		},
+		"key.0123abcd": {{Service: "api", Target: "/etc/app/key.pem"}},
+	}
+	inSwarm := []string{"db.267da420", "db.aaaaaaaa", "other.bbbbbbbb"}
+
+	entries := buildObjectInventory(referenced, inSwarm)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %#v", entries)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		got[e.Name] = e.Status
+	}
+	want := map[string]string{
+		"db.267da420":  objectStatusOK,
+		"db.aaaaaaaa":  objectStatusUnreferenced,
+		"key.0123abcd": objectStatusMissing,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("statuses mismatch\n got:%#v\nwant:%#v", got, want)
+	}
+	if entries[0].Name != "db.267da420" || entries[2].BaseName != "key" {
+		t.Fatalf("entries not sorted: %#v", entries)
+	}
+	if countObjectStatus(entries, objectStatusMissing) != 1 {
+		t.Fatalf("missing count mismatch")
+	}
+}
+
+func TestPrintObjectInventory(t *testing.T) {
+	entries := []objectInventoryEntry{
+		{BaseName: "db", Name: "db.267da420", Status: objectStatusOK, Mounts: []objectMount{{Service: "api", Target: "db"}}},
+		{BaseName: "db", Name: "db.aaaaaaaa", Status: objectStatusUnreferenced},
+	}
+	var buf bytes.Buffer
+	if err := printObjectInventory(&buf, entries); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"VERSION", "db.267da420", "api", objectStatusUnreferenced} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	if lines := strings.Count(out, "\n"); lines != 3 {
+		t.Fatalf("expected header + 2 rows, got %d:\n%s", lines, out)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Make external calls overridable for tests
+var dockerImagePush = func(image string, verbose bool) (string, error) {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "push", image})
+		return "", nil
+	}
+	out, err := runCommandOutput([]string{"docker", "push", image}, verbose)
+	if verbose {
+		fmt.Print(out)
+	}
+	if err != nil {
+		return out, fmt.Errorf("%w: %s", err, lastLine(out))
+	}
+	return out, nil
+}
+
+// pushRetryDelay is the wait before the first retry; it doubles after each
+// failed attempt.
+var pushRetryDelay = 2 * time.Second
+
+var pushDigestRe = regexp.MustCompile(`digest: (sha256:[0-9a-f]{64})`)
+
+// parsePushDigest extracts the manifest digest from `docker push` output.
+func parsePushDigest(output string) string {
+	m := pushDigestRe.FindStringSubmatch(output)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
+
+// pushImage pushes image, retrying up to retries extra times, and returns
+// the pushed digest.
+func pushImage(image string, retries int, verbose bool) (string, error) {
+	delay := pushRetryDelay
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			fmt.Printf("⚠️ Push of %s failed (%v), retrying in %s (%d/%d)\n", image, err, delay, attempt, retries)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var out string
+		out, err = dockerImagePush(image, verbose)
+		if err == nil {
+			return parsePushDigest(out), nil
+		}
+	}
+	return "", err
+}
+
+// pushResult is the outcome of publishing the image of one service.
+type pushResult struct {
+	Service string
+	Image   string
+	Digest  string
+	Err     error
+}
+
+// pushServiceImages pushes the image of each service once, even when several
+// services share it.
+func pushServiceImages(images map[string]string, services []string, retries int, verbose bool) []pushResult {
+	pushed := map[string]pushResult{}
+	var results []pushResult
+	for _, svc := range services {
+		image := images[svc]
+		res, ok := pushed[image]
+		if !ok {
+			res.Image = image
+			res.Digest, res.Err = pushImage(image, retries, verbose)
+			pushed[image] = res
+		}
+		res.Service = svc
+		results = append(results, res)
+	}
+	return results
+}
+
+func countPushFailures(results []pushResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// printPushSummary renders one row per service with the pushed digest.
+func printPushSummary(w io.Writer, results []pushResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tIMAGE\tDIGEST")
+	for _, r := range results {
+		digest := r.Digest
+		switch {
+		case r.Err != nil:
+			digest = "❌ " + r.Err.Error()
+		case digest == "":
+			digest = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Service, r.Image, digest)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const pushOutputFixture = `The push refers to repository [registry:5000/api]
+5f70bf18a086: Layer already exists
+1.2.3: digest: sha256:4c3f8f5c7d0f6a4f1f9d0b1c2e3a4b5c6d7e8f90a1b2c3d4e5f6a7b8c9d0e1f2 size: 1570
+`
+
+func TestParsePushDigest(t *testing.T) {
+	got := parsePushDigest(pushOutputFixture)
+	if got != "sha256:4c3f8f5c7d0f6a4f1f9d0b1c2e3a4b5c6d7e8f90a1b2c3d4e5f6a7b8c9d0e1f2" {
+		t.Fatalf("digest mismatch: %q", got)
+	}
+	if parsePushDigest("no digest here") != "" {
+		t.Fatalf("expected empty digest")
+	}
+}
+
+func TestPushImage_Retries(t *testing.T) {
+	origPush, origDelay := dockerImagePush, pushRetryDelay
+	t.Cleanup(func() { dockerImagePush, pushRetryDelay = origPush, origDelay })
+	pushRetryDelay = 0
+
+	attempts := 0
+	dockerImagePush = func(image string, verbose bool) (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "", errors.New("connection reset")
+		}
+		return pushOutputFixture, nil
+	}
+	digest, err := pushImage("registry:5000/api:1.2.3", 2, false)
+	if err != nil || !strings.HasPrefix(digest, "sha256:") || attempts != 3 {
+		t.Fatalf("pushImage = %q, %v after %d attempts", digest, err, attempts)
+	}
+
+	attempts = 0
+	dockerImagePush = func(image string, verbose bool) (string, error) {
+		attempts++
+		return "", errors.New("unauthorized")
+	}
+	if _, err = pushImage("registry:5000/api:1.2.3", 1, false); err == nil || attempts != 2 {
+		t.Fatalf("expected failure after 2 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestPushServiceImages_SharedImage(t *testing.T) {
+	origPush := dockerImagePush
+	t.Cleanup(func() { dockerImagePush = origPush })
+
+	var pushed []string
+	dockerImagePush = func(image string, verbose bool) (string, error) {
+		pushed = append(pushed, image)
+		if strings.Contains(image, "broken") {
+			return "", errors.New("denied")
+		}
+		return pushOutputFixture, nil
+	}
+
+	images := map[string]string{
+		"api":    "registry:5000/app:1.2.3",
+		"worker": "registry:5000/app:1.2.3",
+		"cron":   "registry:5000/broken:1.2.3",
+	}
+	results := pushServiceImages(images, []string{"api", "cron", "worker"}, 0, false)
+	if len(pushed) != 2 {
+		t.Fatalf("shared image should be pushed once: %v", pushed)
+	}
+	if len(results) != 3 || results[2].Service != "worker" || results[2].Digest != results[0].Digest {
+		t.Fatalf("results mismatch: %#v", results)
+	}
+	if countPushFailures(results) != 1 {
+		t.Fatalf("expected one failure: %#v", results)
+	}
+
+	var buf bytes.Buffer
+	if err := printPushSummary(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "❌ denied") || !strings.Contains(buf.String(), "worker") {
+		t.Fatalf("summary mismatch:\n%s", buf.String())
+	}
+}
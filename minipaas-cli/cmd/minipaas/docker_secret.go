@@ -1,37 +1,111 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"os/exec"
+	"slices"
+	"sort"
+
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
 )
 
 // Make external calls overridable for tests
 var dockerSecretInspect = func(name string) error {
-	return exec.Command("docker", "secret", "inspect", name).Run()
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		_, err := c.SecretInspect(ctx, name)
+		return err
+	})
+}
+
+var dockerSecretCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "secret", "create", name, "-"})
+		return nil
+	}
+	if verbose {
+		fmt.Printf("🔹 Creating secret: %s\n", name)
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		_, err := c.SecretCreate(ctx, dockerapi.SecretSpec{Name: name, Data: content, Labels: labels})
+		return err
+	})
+}
+
+var dockerSecretList = func(filters dockerapi.Filters) ([]string, error) {
+	var names []string
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		objects, err := c.SecretList(ctx, filters)
+		for _, o := range objects {
+			names = append(names, o.Spec.Name)
+		}
+		return err
+	})
+	return names, err
 }
 
-var dockerSecretCreate = func(name string, content []byte, verbose bool) error {
-	return runCommandWithInput([]string{"docker", "secret", "create", name, "-"}, content, verbose)
+// dockerSecretIDs maps the given secret names to their IDs; names that do not
+// exist are left out.
+var dockerSecretIDs = func(names []string) (map[string]string, error) {
+	ids := map[string]string{}
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		objects, err := c.SecretList(ctx, dockerapi.Filters{"name": names})
+		for _, o := range objects {
+			// the name filter matches prefixes
+			if slices.Contains(names, o.Spec.Name) {
+				ids[o.Spec.Name] = o.ID
+			}
+		}
+		return err
+	})
+	return ids, err
+}
+
+var dockerSecretRemove = func(name string, verbose bool) error {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "secret", "rm", name})
+		return nil
+	}
+	if verbose {
+		fmt.Printf("🔹 Removing secret: %s\n", name)
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		return c.SecretRemove(ctx, name)
+	})
 }
 
 func secretExists(name string) bool {
 	return dockerSecretInspect(name) == nil
 }
 
-func secretCreate(baseName string, secret []byte, verbose bool) (string, error) {
+// secretCreate creates the hashed version of baseName for content, labelled
+// with the stack it is created for.
+func secretCreate(stack, baseName string, secret []byte, verbose bool) (string, error) {
 	hash := sha256.Sum256(secret)
-	hashPrefix := hex.EncodeToString(hash[:])[:8]
+	hashPrefix := hex.EncodeToString(hash[:])[:hashSuffixLen]
 
 	secretName := fmt.Sprintf("%s.%s", baseName, hashPrefix)
 
-	return secretName, secretCreateLiteral(secretName, secret, verbose)
+	return secretName, secretCreateLiteral(stack, secretName, secret, verbose)
 }
 
-func secretCreateLiteral(secretName string, secret []byte, verbose bool) error {
+func secretCreateLiteral(stack, secretName string, secret []byte, verbose bool) error {
 	if secretExists(secretName) {
 		return nil
 	}
-	return dockerSecretCreate(secretName, secret, verbose)
+	return dockerSecretCreate(secretName, secret, stackObjectLabels(stack), verbose)
+}
+
+// secretVersions returns the Swarm secrets of stack that are baseName or one
+// of its hashed versions, sorted by name: the ones labelled for stack and the
+// unlabelled ones referenced by its compose files.
+func secretVersions(stack, baseName string, referenced map[string]bool) ([]string, error) {
+	names, err := stackObjects(dockerSecretList, stack, func(name string) bool { return referenced[name] })
+	if err != nil {
+		return nil, err
+	}
+	versions := filterObjectVersions(names, baseName)
+	sort.Strings(versions)
+	return versions, nil
 }
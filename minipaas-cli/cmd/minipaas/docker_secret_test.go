@@ -11,20 +11,22 @@ func TestSecretCreate_NameAndLiteralCall(t *testing.T) {
 	called := false
 	var gotName string
 	var gotBytes []byte
+	var gotLabels map[string]string
 	dockerSecretInspect = func(name string) error { return assertErr }
-	dockerSecretCreate = func(name string, content []byte, verbose bool) error {
+	dockerSecretCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
 		called = true
 		gotName = name
+		gotLabels = labels
 		gotBytes = append([]byte(nil), content...)
 		return nil
 	}
 	t.Cleanup(func() {
 		dockerSecretInspect = func(n string) error { return nil }
-		dockerSecretCreate = func(name string, content []byte, verbose bool) error { return nil }
+		dockerSecretCreate = func(name string, content []byte, labels map[string]string, verbose bool) error { return nil }
 	})
 
 	content := []byte("supersecret")
-	name, err := secretCreate("env", content, false)
+	name, err := secretCreate("minipaas", "env", content, false)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -37,21 +39,49 @@ func TestSecretCreate_NameAndLiteralCall(t *testing.T) {
 	if !called || gotName != want || !reflect.DeepEqual(gotBytes, content) {
 		t.Fatalf("secretCreate did not forward correctly")
 	}
+	if gotLabels[objectStackLabel] != "minipaas" {
+		t.Fatalf("expected the stack label, got %v", gotLabels)
+	}
 }
 
 func TestSecretCreateLiteral_ExistsSkips(t *testing.T) {
 	invoked := false
 	dockerSecretInspect = func(name string) error { return nil }
-	dockerSecretCreate = func(name string, content []byte, verbose bool) error { invoked = true; return nil }
+	dockerSecretCreate = func(name string, content []byte, labels map[string]string, verbose bool) error {
+		invoked = true
+		return nil
+	}
 	t.Cleanup(func() {
 		dockerSecretInspect = func(n string) error { return nil }
-		dockerSecretCreate = func(name string, content []byte, verbose bool) error { return nil }
+		dockerSecretCreate = func(name string, content []byte, labels map[string]string, verbose bool) error { return nil }
 	})
 
-	if err := secretCreateLiteral("sec", []byte("x"), false); err != nil {
+	if err := secretCreateLiteral("minipaas", "sec", []byte("x"), false); err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 	if invoked {
 		t.Fatalf("runner should not be invoked when exists=true")
 	}
 }
+
+func TestSecretVersions(t *testing.T) {
+	orig := dockerSecretList
+	t.Cleanup(func() { dockerSecretList = orig })
+	dockerSecretList = fakeObjectList(map[string]string{
+		"db.0123abcd":    "minipaas",
+		"other.267da420": "minipaas",
+		"db.267da420":    "",
+		"db.89abcdef":    "",
+		"db.fedcba98":    "shop", // same name, owned by another stack
+	})
+
+	// db.89abcdef is unlabelled and not referenced: it may be another stack's
+	got, err := secretVersions("minipaas", "db", map[string]bool{"db.267da420": true})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	want := []string{"db.0123abcd", "db.267da420"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions mismatch\n got:%#v\nwant:%#v", got, want)
+	}
+}
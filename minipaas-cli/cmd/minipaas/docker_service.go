@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+// swarmService is a service as returned by the Engine API and by
+// `docker service inspect`.
+type swarmService = dockerapi.Service
+
+// Make external calls overridable for tests
+var dockerServiceList = func() ([]swarmService, error) {
+	var services []swarmService
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) (err error) {
+		services, err = c.ServiceList(ctx, nil)
+		return err
+	})
+	return services, err
+}
+
+var dockerServiceInspect = func(service string) (swarmService, error) {
+	var svc swarmService
+	err := withDockerClient(func(ctx context.Context, c *dockerapi.Client) (err error) {
+		svc, err = c.ServiceInspect(ctx, service)
+		return err
+	})
+	return svc, err
+}
+
+var dockerServiceCreate = func(spec any) error {
+	if dryRun {
+		name, image := serviceSpecSummary(spec)
+		printDryRunCommand([]string{"docker", "service", "create", "--name", name, image})
+		return nil
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		_, err := c.ServiceCreate(ctx, spec)
+		return err
+	})
+}
+
+var dockerServiceRemove = func(service string) error {
+	if dryRun {
+		printDryRunCommand([]string{"docker", "service", "rm", service})
+		return nil
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		return c.ServiceRemove(ctx, service)
+	})
+}
+
+// dockerServiceUpdateSpec replaces the spec of a service, read at version.
+// It returns once the update is accepted, not once it converged.
+var dockerServiceUpdateSpec = func(service string, version uint64, spec any) error {
+	if dryRun {
+		_, image := serviceSpecSummary(spec)
+		printDryRunCommand([]string{"docker", "service", "update", "--image", image, service})
+		return nil
+	}
+	return withDockerClient(func(ctx context.Context, c *dockerapi.Client) error {
+		warnings, err := c.ServiceUpdate(ctx, service, version, spec)
+		for _, w := range warnings {
+			log.Printf("⚠️ %s: %s", service, w)
+		}
+		return err
+	})
+}
+
+// dockerServiceLogs streams the logs of a service until ctx is done.
+var dockerServiceLogs = func(ctx context.Context, service string, stdout, stderr io.Writer) error {
+	return withDockerClient(func(_ context.Context, c *dockerapi.Client) error {
+		return c.ServiceLogs(ctx, service, true, stdout, stderr)
+	})
+}
+
+// dockerServiceUpdate runs `docker service update` without detaching, so it
+// only returns once the update has converged (or failed).
+var dockerServiceUpdate = func(service string, flags []string, verbose bool) error {
+	cmd := append([]string{"docker", "service", "update", "--detach=false"}, flags...)
+	return runCommand(append(cmd, service), verbose)
+}
+
+// serviceSpecSummary returns the name and image of a raw service spec, for
+// dry-run output.
+func serviceSpecSummary(spec any) (name, image string) {
+	data, _ := json.Marshal(spec)
+	var s dockerapi.ServiceSpec
+	_ = json.Unmarshal(data, &s)
+	return s.Name, s.TaskTemplate.ContainerSpec.Image
+}
+
+func parseServiceInspect(data []byte) ([]swarmService, error) {
+	var services []swarmService
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// serviceMountedSecrets maps each secret name to the services that mount it.
+func serviceMountedSecrets(services []swarmService) map[string][]string {
+	mounted := map[string][]string{}
+	for _, svc := range services {
+		for _, sec := range svc.Spec.TaskTemplate.ContainerSpec.Secrets {
+			mounted[sec.SecretName] = append(mounted[sec.SecretName], svc.Spec.Name)
+		}
+	}
+	return mounted
+}
+
+// serviceMountedConfigs maps each config name to the services that mount it.
+func serviceMountedConfigs(services []swarmService) map[string][]string {
+	mounted := map[string][]string{}
+	for _, svc := range services {
+		for _, cfg := range svc.Spec.TaskTemplate.ContainerSpec.Configs {
+			mounted[cfg.ConfigName] = append(mounted[cfg.ConfigName], svc.Spec.Name)
+		}
+	}
+	return mounted
+}
+
+// findSwarmService returns the service with the given name, if any.
+func findSwarmService(services []swarmService, name string) (swarmService, bool) {
+	for _, svc := range services {
+		if svc.Spec.Name == name {
+			return svc, true
+		}
+	}
+	return swarmService{}, false
+}
+
+// fileMountOption renders a secret/config reference in the format expected by
+// --secret-add and --config-add.
+func fileMountOption(ref types.FileReferenceConfig) string {
+	opt := "source=" + ref.Source
+	if ref.Target != "" {
+		opt += ",target=" + ref.Target
+	}
+	if ref.UID != "" {
+		opt += ",uid=" + ref.UID
+	}
+	if ref.GID != "" {
+		opt += ",gid=" + ref.GID
+	}
+	if ref.Mode != nil {
+		opt += fmt.Sprintf(",mode=%#o", uint32(*ref.Mode))
+	}
+	return opt
+}
+
+// secretSwapFlags builds the update flags that unmount the remove secrets and
+// mount the add references in a single service update.
+func secretSwapFlags(remove []string, add []types.ServiceSecretConfig) []string {
+	var flags []string
+	for _, name := range remove {
+		flags = append(flags, "--secret-rm", name)
+	}
+	for _, ref := range add {
+		flags = append(flags, "--secret-add", fileMountOption(types.FileReferenceConfig(ref)))
+	}
+	return flags
+}
+
+// configSwapFlags is the config counterpart of secretSwapFlags.
+func configSwapFlags(remove []string, add []types.ServiceConfigObjConfig) []string {
+	var flags []string
+	for _, name := range remove {
+		flags = append(flags, "--config-rm", name)
+	}
+	for _, ref := range add {
+		flags = append(flags, "--config-add", fileMountOption(types.FileReferenceConfig(ref)))
+	}
+	return flags
+}
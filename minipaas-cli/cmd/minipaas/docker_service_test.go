@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+const serviceInspectFixture = `[
+  {
+    "ID": "svc1",
+    "Spec": {
+      "Name": "minipaas_api",
+      "Labels": {"com.docker.stack.namespace": "minipaas"},
+      "TaskTemplate": {
+        "ContainerSpec": {
+          "Image": "registry:5000/api:1.0.0@sha256:abc",
+          "Secrets": [
+            {"File": {"Name": "db", "UID": "0", "GID": "0", "Mode": 292}, "SecretID": "s1", "SecretName": "db.267da420"}
+          ],
+          "Configs": [
+            {"File": {"Name": "app.conf", "UID": "0", "GID": "0", "Mode": 292}, "ConfigID": "c1", "ConfigName": "app.conf.0123abcd"}
+          ]
+        }
+      }
+    }
+  },
+  {
+    "ID": "svc2",
+    "Spec": {
+      "Name": "minipaas_worker",
+      "TaskTemplate": {
+        "ContainerSpec": {
+          "Image": "registry:5000/api:1.0.0",
+          "Secrets": [
+            {"File": {"Name": "db"}, "SecretID": "s1", "SecretName": "db.267da420"}
+          ]
+        }
+      }
+    }
+  }
+]`
+
+func TestParseServiceInspect(t *testing.T) {
+	services, err := parseServiceInspect([]byte(serviceInspectFixture))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(services) != 2 || services[0].Spec.Name != "minipaas_api" {
+		t.Fatalf("services mismatch: %#v", services)
+	}
+	sec := services[0].Spec.TaskTemplate.ContainerSpec.Secrets[0]
+	if sec.SecretName != "db.267da420" || sec.File == nil || sec.File.Name != "db" || sec.File.Mode != 0444 {
+		t.Fatalf("secret ref mismatch: %#v", sec)
+	}
+}
+
+func TestServiceMountedSecretsAndConfigs(t *testing.T) {
+	services, err := parseServiceInspect([]byte(serviceInspectFixture))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	secrets := serviceMountedSecrets(services)
+	want := []string{"minipaas_api", "minipaas_worker"}
+	if !reflect.DeepEqual(secrets["db.267da420"], want) {
+		t.Fatalf("mounted secrets mismatch: %#v", secrets)
+	}
+	configs := serviceMountedConfigs(services)
+	if !reflect.DeepEqual(configs["app.conf.0123abcd"], []string{"minipaas_api"}) {
+		t.Fatalf("mounted configs mismatch: %#v", configs)
+	}
+}
+
+func TestSecretSwapFlags(t *testing.T) {
+	mode := types.FileMode(0400)
+	flags := secretSwapFlags(
+		[]string{"db.0123abcd"},
+		[]types.ServiceSecretConfig{{Source: "db.267da420", Target: "/etc/app/db", UID: "1000", GID: "1000", Mode: &mode}},
+	)
+	want := []string{
+		"--secret-rm", "db.0123abcd",
+		"--secret-add", "source=db.267da420,target=/etc/app/db,uid=1000,gid=1000,mode=0400",
+	}
+	if !reflect.DeepEqual(flags, want) {
+		t.Fatalf("flags mismatch\n got:%#v\nwant:%#v", flags, want)
+	}
+}
+
+func TestConfigSwapFlags(t *testing.T) {
+	flags := configSwapFlags(nil, []types.ServiceConfigObjConfig{{Source: "app.conf.267da420", Target: "/app.conf"}})
+	want := []string{"--config-add", "source=app.conf.267da420,target=/app.conf"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Fatalf("flags mismatch\n got:%#v\nwant:%#v", flags, want)
+	}
+}
+
+func TestFindSwarmService(t *testing.T) {
+	services, err := parseServiceInspect([]byte(serviceInspectFixture))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if svc, ok := findSwarmService(services, "minipaas_worker"); !ok || svc.ID != "svc2" {
+		t.Fatalf("worker not found: %#v", svc)
+	}
+	if _, ok := findSwarmService(services, "minipaas_missing"); ok {
+		t.Fatalf("unexpected service found")
+	}
+}
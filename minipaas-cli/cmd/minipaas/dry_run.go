@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// dryRun is set by the global --dry-run flag. Commands still read local files
+// and the cluster, but every write and every mutating docker or openssl call
+// is printed instead of performed.
+var dryRun bool
+
+// dryRunOutput is where dry-run reports are written.
+var dryRunOutput io.Writer = os.Stdout
+
+// maxDryRunArg is the longest argument printed verbatim; longer ones, such as
+// a Caddy config posted to the admin API, are elided.
+const maxDryRunArg = 120
+
+// printDryRunCommand reports a command that would have been run.
+func printDryRunCommand(cmd []string) {
+	quoted := make([]string, len(cmd))
+	for i, arg := range cmd {
+		if len(arg) > maxDryRunArg {
+			arg = fmt.Sprintf("%s...(%d bytes)", arg[:maxDryRunArg/2], len(arg))
+		}
+		quoted[i] = shellQuote(arg)
+	}
+	fmt.Fprintf(dryRunOutput, "🔹 [dry-run] %s\n", strings.Join(quoted, " "))
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?;&|<>(){}[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeFile writes data to fn. In dry-run mode it prints a unified diff of
+// the change instead.
+func writeFile(fn string, data []byte, perm os.FileMode) error {
+	if !dryRun {
+		return os.WriteFile(fn, data, perm)
+	}
+
+	current, err := os.ReadFile(fn)
+	from := fn
+	if errors.Is(err, os.ErrNotExist) {
+		from = "/dev/null"
+	} else if err != nil {
+		return err
+	}
+	if bytes.Equal(current, data) {
+		fmt.Fprintf(dryRunOutput, "🔹 [dry-run] %s unchanged\n", fn)
+		return nil
+	}
+	fmt.Fprintf(dryRunOutput, "🔹 [dry-run] would write %s\n", fn)
+	fmt.Fprint(dryRunOutput, unifiedDiff(from, fn, string(current), string(data)))
+	return nil
+}
+
+// mkdirAll creates a directory tree, unless in dry-run mode.
+func mkdirAll(path string, perm os.FileMode) error {
+	if dryRun {
+		return nil
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffLine struct {
+	Op   byte // ' ', '-' or '+'
+	Text string
+	// A and B are the 0-based line numbers in the old and new text before
+	// this line.
+	A, B int
+}
+
+// unifiedDiff renders the difference between a and b in unified format.
+func unifiedDiff(fromName, toName, a, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(lines); {
+		// Find the next change and extend the hunk while changes are close.
+		first := start
+		for first < len(lines) && lines[first].Op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		last := first
+		for i := first; i < len(lines); i++ {
+			if lines[i].Op != ' ' {
+				if i-last > 2*diffContext {
+					break
+				}
+				last = i
+			}
+		}
+		from := max(first-diffContext, 0)
+		to := min(last+diffContext+1, len(lines))
+
+		aLen, bLen := 0, 0
+		for _, l := range lines[from:to] {
+			if l.Op != '+' {
+				aLen++
+			}
+			if l.Op != '-' {
+				bLen++
+			}
+		}
+		aStart, bStart := lines[from].A, lines[from].B
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, l := range lines[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", l.Op, l.Text)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a line edit script from a to b using their longest
+// common subsequence.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	return lines
+}
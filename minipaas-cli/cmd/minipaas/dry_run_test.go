@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexflint/go-arg"
+)
+
+func withDryRun(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	origDryRun, origOut := dryRun, dryRunOutput
+	t.Cleanup(func() { dryRun, dryRunOutput = origDryRun, origOut })
+	var out bytes.Buffer
+	dryRun, dryRunOutput = true, &out
+	return &out
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	want := `--- a.yaml
++++ b.yaml
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	if got := unifiedDiff("a.yaml", "b.yaml", a, b); got != want {
+		t.Fatalf("unifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+
+	want = "--- /dev/null\n+++ new.yaml\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got := unifiedDiff("/dev/null", "new.yaml", "", "a\nb\n"); got != want {
+		t.Fatalf("unifiedDiff for new file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteFile_DryRun(t *testing.T) {
+	out := withDryRun(t)
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "minipaas.yaml")
+	if err := os.WriteFile(fn, []byte("deploy:\n  version: 1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(fn, []byte("deploy:\n  version: 1.1.0\n"), 0644); err != nil {
+		t.Fatalf("writeFile: %v", err)
+	}
+	if data, _ := os.ReadFile(fn); !strings.Contains(string(data), "1.0.0") {
+		t.Fatalf("dry-run must not write the file, got %q", data)
+	}
+	if !strings.Contains(out.String(), "-  version: 1.0.0\n+  version: 1.1.0\n") {
+		t.Fatalf("expected diff in output:\n%s", out.String())
+	}
+
+	out.Reset()
+	created := filepath.Join(dir, "caddy.json")
+	if err := writeFile(created, []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("writeFile: %v", err)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Fatalf("dry-run must not create the file")
+	}
+	if !strings.Contains(out.String(), "--- /dev/null") {
+		t.Fatalf("expected new file diff:\n%s", out.String())
+	}
+}
+
+func TestRunCommand_DryRun(t *testing.T) {
+	out := withDryRun(t)
+	if err := runCommand([]string{"docker", "stack", "deploy", "-c", "my compose.yaml", "minipaas"}, false); err != nil {
+		t.Fatalf("runCommand: %v", err)
+	}
+	if got := out.String(); got != "🔹 [dry-run] docker stack deploy -c 'my compose.yaml' minipaas\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+
+	out.Reset()
+	if got, err := runCommandOutput([]string{"docker", "push", "registry:5000/api:1.0.0"}, false); err != nil || got != "" {
+		t.Fatalf("runCommandOutput: %q, %v", got, err)
+	}
+	if got := out.String(); got != "🔹 [dry-run] docker push registry:5000/api:1.0.0\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+
+	out.Reset()
+	if err := runContainerExec("cid", []string{"wget", "--post-data=" + strings.Repeat("x", 500)}, nil, nil); err != nil {
+		t.Fatalf("runContainerExec: %v", err)
+	}
+	if !strings.Contains(out.String(), "docker exec cid wget") || !strings.Contains(out.String(), "(512 bytes)") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestDryRunFlagAfterSubcommand(t *testing.T) {
+	dest := args
+	p, err := arg.NewParser(arg.Config{}, &dest)
+	if err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+	if err := p.Parse([]string{"deploy", "rollout", "--env", "prod", "--dry-run"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !dest.DryRun || dest.DeploySubcommand == nil || dest.DeploySubcommand.DeployRollout == nil {
+		t.Fatalf("expected dry-run rollout, got %+v", dest)
+	}
+}
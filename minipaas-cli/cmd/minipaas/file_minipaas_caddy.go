@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -199,8 +201,9 @@ func replaceOrAppendRoute(server map[string]interface{}, newRoute map[string]int
 
 // caddyUpdateConfigAddRoute reads env/caddy.json as a full Caddy config (wrapping
 // server-only JSON if needed), ensures apps.http.servers.minipaas exists,
-// adds or replaces the route matching the given domain+path, and writes back.
-func caddyUpdateConfigAddRoute(env, url, target string) (string, error) {
+// adds or replaces the route matching the given domain+path to the target
+// service of stack, and writes back.
+func caddyUpdateConfigAddRoute(env, stack, url, target string) (string, error) {
 	fn := filepath.Join(env, caddyFile)
 
 	publicURL, err := parsePublicURL(url)
@@ -218,7 +221,7 @@ func caddyUpdateConfigAddRoute(env, url, target string) (string, error) {
 
 	// Parse target service[:port]
 	service, svcPort := splitTarget(target)
-	upstreamDial := fmt.Sprintf("minipaas_%s:%s", service, svcPort)
+	upstreamDial := stackService(stack, service) + ":" + svcPort
 
 	// Load the file; handle both full config and server-only JSON
 	data, err := os.ReadFile(fn)
@@ -247,8 +250,322 @@ func caddyUpdateConfigAddRoute(env, url, target string) (string, error) {
 	if err != nil {
 		return fn, err
 	}
-	if err := os.WriteFile(fn, out, 0644); err != nil {
+	if err := writeFile(fn, out, 0644); err != nil {
 		return fn, err
 	}
 	return fn, nil
 }
+
+// canaryDial returns the dial of the canary sibling of a <stack>_<svc>:<port>
+// upstream.
+func canaryDial(dial string) string {
+	service, port := splitTarget(dial)
+	return service + canarySuffix + ":" + port
+}
+
+// setCanaryUpstream makes a reverse_proxy handler split traffic between its
+// upstream for service and the canary sibling, weight percent going to the
+// canary. A weight of 0 drops the canary and the weighted policy again. It
+// reports whether the handler proxies to service, given with its stack prefix.
+func setCanaryUpstream(handler map[string]interface{}, service string, weight int) bool {
+	upstreams, _ := handler["upstreams"].([]interface{})
+	var main map[string]interface{}
+	for _, u := range upstreams {
+		up, ok := u.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		dial, _ := up["dial"].(string)
+		if strings.HasPrefix(dial, service+":") {
+			main = up
+			break
+		}
+	}
+	if main == nil {
+		return false
+	}
+
+	if weight == 0 {
+		handler["upstreams"] = []interface{}{main}
+		delete(handler, "load_balancing")
+		return true
+	}
+	handler["upstreams"] = []interface{}{
+		main,
+		map[string]interface{}{"dial": canaryDial(main["dial"].(string))},
+	}
+	handler["load_balancing"] = map[string]interface{}{
+		"selection_policy": map[string]interface{}{
+			"policy":  "weighted_round_robin",
+			"weights": []interface{}{100 - weight, weight},
+		},
+	}
+	return true
+}
+
+// updateProxies applies fn to every reverse_proxy handler of routes,
+// descending into subroutes, and returns how many handlers fn changed.
+func updateProxies(routes []interface{}, fn func(handler map[string]interface{}) bool) int {
+	changed := 0
+	for _, r := range routes {
+		route, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		handlers, _ := route["handle"].([]interface{})
+		for _, h := range handlers {
+			handler, ok := h.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch handler["handler"] {
+			case "reverse_proxy":
+				if fn(handler) {
+					changed++
+				}
+			case "subroute":
+				sub, _ := handler["routes"].([]interface{})
+				changed += updateProxies(sub, fn)
+			}
+		}
+	}
+	return changed
+}
+
+// caddyUpdateProxies applies fn to every reverse_proxy handler of
+// env/caddy.json and writes the file back if any was changed. It returns how
+// many handlers were changed.
+func caddyUpdateProxies(env string, update func(handler map[string]interface{}) bool) (string, int, error) {
+	fn, payload, err := caddyLoadServers(env)
+	if err != nil {
+		return fn, 0, err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(payload, &root); err != nil {
+		return fn, 0, err
+	}
+
+	changed := 0
+	for _, routes := range serverRoutes(root) {
+		changed += updateProxies(routes, update)
+	}
+	if changed == 0 {
+		return fn, 0, nil
+	}
+
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return fn, 0, err
+	}
+	return fn, changed, writeFile(fn, out, 0644)
+}
+
+// serverRoutes returns the routes of every server of a Caddy config.
+func serverRoutes(root map[string]interface{}) [][]interface{} {
+	apps, _ := root["apps"].(map[string]interface{})
+	httpApp, _ := apps["http"].(map[string]interface{})
+	servers, _ := httpApp["servers"].(map[string]interface{})
+
+	var all [][]interface{}
+	for _, s := range servers {
+		server, _ := s.(map[string]interface{})
+		if routes, ok := server["routes"].([]interface{}); ok {
+			all = append(all, routes)
+		}
+	}
+	return all
+}
+
+// caddyUpdateConfigCanary adds the canary of service as a weighted upstream
+// to every route of env/caddy.json proxying to it, or removes it when weight
+// is 0, and writes back. It returns how many routes were changed.
+func caddyUpdateConfigCanary(env, stack, service string, weight int) (string, int, error) {
+	if weight < 0 || weight > 99 {
+		return filepath.Join(env, caddyFile), 0, fmt.Errorf("canary weight must be between 0 and 99, got %d", weight)
+	}
+	return caddyUpdateProxies(env, func(handler map[string]interface{}) bool {
+		return setCanaryUpstream(handler, stackService(stack, service), weight)
+	})
+}
+
+// caddyUpdateConfigDial points every upstream dialing <stack>_<from> to
+// <stack>_<to> on the same port, and writes back. It returns how many routes
+// were changed.
+func caddyUpdateConfigDial(env, stack, from, to string) (string, int, error) {
+	return caddyUpdateProxies(env, func(handler map[string]interface{}) bool {
+		upstreams, _ := handler["upstreams"].([]interface{})
+		changed := false
+		for _, u := range upstreams {
+			up, ok := u.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			dial, _ := up["dial"].(string)
+			if port, ok := strings.CutPrefix(dial, stackService(stack, from)+":"); ok {
+				up["dial"] = stackService(stack, to) + ":" + port
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// caddyRoutesTo returns how many reverse_proxy handlers of env/caddy.json
+// dial <stack>_<service>.
+func caddyRoutesTo(env, stack, service string) (int, error) {
+	_, payload, err := caddyLoadServers(env)
+	if err != nil {
+		return 0, err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(payload, &root); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, routes := range serverRoutes(root) {
+		count += updateProxies(routes, func(handler map[string]interface{}) bool {
+			upstreams, _ := handler["upstreams"].([]interface{})
+			for _, u := range upstreams {
+				up, _ := u.(map[string]interface{})
+				if dial, _ := up["dial"].(string); strings.HasPrefix(dial, stackService(stack, service)+":") {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return count, nil
+}
+
+// routeDials returns the upstream dials of every reverse_proxy handler of a
+// route, subroutes included.
+func routeDials(route map[string]interface{}) []string {
+	var dials []string
+	updateProxies([]interface{}{route}, func(handler map[string]interface{}) bool {
+		upstreams, _ := handler["upstreams"].([]interface{})
+		for _, u := range upstreams {
+			up, _ := u.(map[string]interface{})
+			if dial, _ := up["dial"].(string); dial != "" {
+				dials = append(dials, dial)
+			}
+		}
+		return false
+	})
+	return dials
+}
+
+// foreignRoute reports whether a route proxies to services of other stacks
+// only, so that it belongs to another environment sharing Caddy.
+func foreignRoute(route map[string]interface{}, stack string) bool {
+	dials := routeDials(route)
+	for _, dial := range dials {
+		if strings.HasPrefix(dial, stack+"_") {
+			return false
+		}
+	}
+	return len(dials) > 0
+}
+
+// mergeCaddyRoutes adds to the env Caddy config the routes of the live one
+// that belong to other stacks, with their listen addresses and server
+// settings, so loading it does not drop the routes of the environments
+// sharing the same Caddy. Routes of the env config win on the same
+// host+path. An empty or null live config leaves the env config as is.
+func mergeCaddyRoutes(own, live []byte, stack string) ([]byte, error) {
+	live = bytes.TrimSpace(live)
+	if len(live) == 0 || string(live) == "null" {
+		return own, nil
+	}
+
+	var root, liveRoot map[string]interface{}
+	if err := json.Unmarshal(own, &root); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(live, &liveRoot); err != nil {
+		return nil, fmt.Errorf("invalid live Caddy config: %w", err)
+	}
+
+	liveApps, _ := liveRoot["apps"].(map[string]interface{})
+	liveHTTP, _ := liveApps["http"].(map[string]interface{})
+	liveServers, _ := liveHTTP["servers"].(map[string]interface{})
+	if len(liveServers) == 0 {
+		return own, nil
+	}
+
+	if root == nil {
+		root = map[string]interface{}{}
+	}
+	apps, _ := root["apps"].(map[string]interface{})
+	if apps == nil {
+		apps = map[string]interface{}{}
+		root["apps"] = apps
+	}
+	httpApp, _ := apps["http"].(map[string]interface{})
+	if httpApp == nil {
+		httpApp = map[string]interface{}{}
+		apps["http"] = httpApp
+	}
+	servers, _ := httpApp["servers"].(map[string]interface{})
+	if servers == nil {
+		servers = map[string]interface{}{}
+		httpApp["servers"] = servers
+	}
+
+	for name, s := range liveServers {
+		liveServer, _ := s.(map[string]interface{})
+		var foreign []interface{}
+		liveRoutes, _ := liveServer["routes"].([]interface{})
+		for _, r := range liveRoutes {
+			if route, ok := r.(map[string]interface{}); ok && foreignRoute(route, stack) {
+				foreign = append(foreign, route)
+			}
+		}
+		if len(foreign) == 0 {
+			continue
+		}
+
+		server, _ := servers[name].(map[string]interface{})
+		if server == nil {
+			server = map[string]interface{}{}
+			servers[name] = server
+		}
+		for key, value := range liveServer {
+			switch key {
+			case "routes":
+			case "listen":
+				listen, _ := server["listen"].([]interface{})
+				for _, l := range value.([]interface{}) {
+					if !slices.Contains(listen, l) {
+						listen = append(listen, l)
+					}
+				}
+				server["listen"] = listen
+			default:
+				if _, ok := server[key]; !ok {
+					server[key] = value
+				}
+			}
+		}
+
+		routes, _ := server["routes"].([]interface{})
+		for _, r := range foreign {
+			rh, rp := routeMatch(r.(map[string]interface{}))
+			taken := false
+			for _, o := range routes {
+				if route, ok := o.(map[string]interface{}); ok {
+					if h, p := routeMatch(route); h == rh && p == rp {
+						taken = true
+						break
+					}
+				}
+			}
+			if !taken {
+				routes = append(routes, r)
+			}
+		}
+		server["routes"] = routes
+	}
+
+	return json.Marshal(root)
+}
@@ -44,9 +44,10 @@ type Match struct {
 
 // Handler supports reverse_proxy and subroute via fields we use.
 type Handler struct {
-	Type      string     `json:"handler"`
-	Upstreams []Upstream `json:"upstreams,omitempty"`
-	Routes    []Route    `json:"routes,omitempty"`
+	Type          string         `json:"handler"`
+	Upstreams     []Upstream     `json:"upstreams,omitempty"`
+	LoadBalancing *LoadBalancing `json:"load_balancing,omitempty"`
+	Routes        []Route        `json:"routes,omitempty"`
 }
 
 // Upstream for reverse_proxy
@@ -54,6 +55,18 @@ type Upstream struct {
 	Dial string `json:"dial,omitempty"`
 }
 
+// LoadBalancing selects how reverse_proxy spreads requests over upstreams.
+type LoadBalancing struct {
+	SelectionPolicy SelectionPolicy `json:"selection_policy"`
+}
+
+// SelectionPolicy is a load balancing policy; Weights is used by
+// weighted_round_robin, one per upstream.
+type SelectionPolicy struct {
+	Policy  string `json:"policy"`
+	Weights []int  `json:"weights,omitempty"`
+}
+
 // AutomaticHTTPS controls Caddy's automatic HTTPS behavior per server.
 type AutomaticHTTPS struct {
 	Disable          bool `json:"disable,omitempty"`
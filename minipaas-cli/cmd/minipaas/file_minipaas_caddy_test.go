@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -28,7 +29,7 @@ func TestCaddyUpdateConfigAddRoute_HTTPS(t *testing.T) {
 		t.Fatalf("write: %v", err)
 	}
 
-	out, err := caddyUpdateConfigAddRoute(dir, "example.com/app", "api:8080")
+	out, err := caddyUpdateConfigAddRoute(dir, "minipaas", "example.com/app", "api:8080")
 	if err != nil {
 		t.Fatalf("caddyUpdateConfigAddRoute error: %v", err)
 	}
@@ -73,7 +74,7 @@ func TestCaddyUpdateConfigAddRoute_HTTP_CustomPort(t *testing.T) {
 		t.Fatalf("write: %v", err)
 	}
 
-	if _, err := caddyUpdateConfigAddRoute(dir, "http://example.com:8081/root", "web:80"); err != nil {
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "http://example.com:8081/root", "web:80"); err != nil {
 		t.Fatalf("caddyUpdateConfigAddRoute error: %v", err)
 	}
 	cfg := readCaddyConfig(t, fn)
@@ -110,7 +111,7 @@ func TestCaddyUpdateConfigAddRoute_ReplaceAndRootPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := caddyUpdateConfigAddRoute(dir, "https://example.org/", "web:80"); err != nil {
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "https://example.org/", "web:80"); err != nil {
 		t.Fatalf("add route: %v", err)
 	}
 	var cfg CaddyConfig
@@ -124,7 +125,7 @@ func TestCaddyUpdateConfigAddRoute_ReplaceAndRootPath(t *testing.T) {
 		t.Fatalf("root path normalization failed: %#v", r.Match[0].Path)
 	}
 
-	if _, err := caddyUpdateConfigAddRoute(dir, "https://example.org/", "web:81"); err != nil {
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "https://example.org/", "web:81"); err != nil {
 		t.Fatalf("replace route: %v", err)
 	}
 	data, _ = os.ReadFile(fn)
@@ -145,10 +146,10 @@ func TestCaddyUpdateConfigAddRoute_AddSecondRouteDifferentPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := caddyUpdateConfigAddRoute(dir, "https://example.org/a", "web:80"); err != nil {
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "https://example.org/a", "web:80"); err != nil {
 		t.Fatalf("first route: %v", err)
 	}
-	if _, err := caddyUpdateConfigAddRoute(dir, "https://example.org/b", "api:80"); err != nil {
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "https://example.org/b", "api:80"); err != nil {
 		t.Fatalf("second route: %v", err)
 	}
 	cfg := readCaddyConfig(t, fn)
@@ -326,3 +327,150 @@ func TestReplaceOrAppendRoute(t *testing.T) {
 		t.Fatalf("expected 2 routes, got %d", len(routes))
 	}
 }
+
+func TestCaddyUpdateConfigCanary(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "caddy.json")
+	if err := os.WriteFile(fn, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "example.com", "api:8080"); err != nil {
+		t.Fatalf("add route: %v", err)
+	}
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "example.com/admin", "admin:80"); err != nil {
+		t.Fatalf("add route: %v", err)
+	}
+
+	_, changed, err := caddyUpdateConfigCanary(dir, "minipaas", "api", 10)
+	if err != nil || changed != 1 {
+		t.Fatalf("expected one route changed, got %d, %v", changed, err)
+	}
+	routes := readCaddyConfig(t, fn).Apps.HTTP.Servers.Minipaas.Routes
+	h := routes[0].Handle[0]
+	if len(h.Upstreams) != 2 || h.Upstreams[0].Dial != "minipaas_api:8080" || h.Upstreams[1].Dial != "minipaas_api_canary:8080" {
+		t.Fatalf("unexpected upstreams: %#v", h.Upstreams)
+	}
+	if h.LoadBalancing == nil || h.LoadBalancing.SelectionPolicy.Policy != "weighted_round_robin" ||
+		!reflect.DeepEqual(h.LoadBalancing.SelectionPolicy.Weights, []int{90, 10}) {
+		t.Fatalf("unexpected load balancing: %#v", h.LoadBalancing)
+	}
+	if len(routes[1].Handle[0].Upstreams) != 1 {
+		t.Fatalf("route of another service must be untouched: %#v", routes[1])
+	}
+
+	// Starting again replaces the weights instead of adding upstreams.
+	if _, changed, err = caddyUpdateConfigCanary(dir, "minipaas", "api", 50); err != nil || changed != 1 {
+		t.Fatalf("expected one route changed, got %d, %v", changed, err)
+	}
+	h = readCaddyConfig(t, fn).Apps.HTTP.Servers.Minipaas.Routes[0].Handle[0]
+	if len(h.Upstreams) != 2 || !reflect.DeepEqual(h.LoadBalancing.SelectionPolicy.Weights, []int{50, 50}) {
+		t.Fatalf("unexpected handler: %#v", h)
+	}
+
+	if _, changed, err = caddyUpdateConfigCanary(dir, "minipaas", "api", 0); err != nil || changed != 1 {
+		t.Fatalf("expected one route restored, got %d, %v", changed, err)
+	}
+	h = readCaddyConfig(t, fn).Apps.HTTP.Servers.Minipaas.Routes[0].Handle[0]
+	if len(h.Upstreams) != 1 || h.Upstreams[0].Dial != "minipaas_api:8080" || h.LoadBalancing != nil {
+		t.Fatalf("expected routing restored, got %#v", h)
+	}
+
+	if _, changed, _ = caddyUpdateConfigCanary(dir, "minipaas", "worker", 10); changed != 0 {
+		t.Fatalf("expected no route for worker, got %d", changed)
+	}
+}
+
+func TestCaddyUpdateConfigDial(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "caddy.json")
+	if err := os.WriteFile(fn, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "example.com", "api:8080"); err != nil {
+		t.Fatalf("add route: %v", err)
+	}
+	if _, err := caddyUpdateConfigAddRoute(dir, "minipaas", "example.com/admin", "api_admin:80"); err != nil {
+		t.Fatalf("add route: %v", err)
+	}
+
+	if n, err := caddyRoutesTo(dir, "minipaas", "api"); err != nil || n != 1 {
+		t.Fatalf("expected one route to api, got %d, %v", n, err)
+	}
+
+	_, changed, err := caddyUpdateConfigDial(dir, "minipaas", "api", "api_green")
+	if err != nil || changed != 1 {
+		t.Fatalf("expected one route changed, got %d, %v", changed, err)
+	}
+	routes := readCaddyConfig(t, fn).Apps.HTTP.Servers.Minipaas.Routes
+	if dial := routes[0].Handle[0].Upstreams[0].Dial; dial != "minipaas_api_green:8080" {
+		t.Fatalf("unexpected dial %q", dial)
+	}
+	if dial := routes[1].Handle[0].Upstreams[0].Dial; dial != "minipaas_api_admin:80" {
+		t.Fatalf("route of another service must be untouched, got %q", dial)
+	}
+	if n, _ := caddyRoutesTo(dir, "minipaas", "api"); n != 0 {
+		t.Fatalf("expected no route left on api, got %d", n)
+	}
+
+	if _, changed, _ = caddyUpdateConfigDial(dir, "minipaas", "api_green", "api"); changed != 1 {
+		t.Fatalf("expected switch back, got %d", changed)
+	}
+	if dial := readCaddyConfig(t, fn).Apps.HTTP.Servers.Minipaas.Routes[0].Handle[0].Upstreams[0].Dial; dial != "minipaas_api:8080" {
+		t.Fatalf("unexpected dial after switch back %q", dial)
+	}
+}
+
+func TestCaddyUpdateConfigAddRoute_Stack(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, caddyFile), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := caddyUpdateConfigAddRoute(dir, "shop", "shop.example.com", "api:8080"); err != nil {
+		t.Fatalf("caddyUpdateConfigAddRoute: %v", err)
+	}
+	if n, _ := caddyRoutesTo(dir, "shop", "api"); n != 1 {
+		t.Fatalf("routes to shop_api = %d", n)
+	}
+	if n, _ := caddyRoutesTo(dir, "minipaas", "api"); n != 0 {
+		t.Fatalf("routes to minipaas_api = %d", n)
+	}
+}
+
+func TestMergeCaddyRoutes(t *testing.T) {
+	own := []byte(`{"apps":{"http":{"servers":{"minipaas":{"listen":[":443"],"routes":[
+		{"match":[{"host":["shop.example.com"],"path":["/*"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"shop_api:8080"}]}]}
+	]}}}}}`)
+	live := []byte(`{"apps":{"http":{"servers":{"minipaas":{"listen":[":443",":8000"],"automatic_https":{"disable":true},"routes":[
+		{"match":[{"host":["shop.example.com"],"path":["/old/*"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"shop_old:80"}]}]},
+		{"match":[{"host":["blog.example.com"],"path":["/*"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"blog_web:80"}]}]},
+		{"match":[{"host":["shop.example.com"],"path":["/*"]}],"handle":[{"handler":"reverse_proxy","upstreams":[{"dial":"other_api:80"}]}]}
+	]}}}}}`)
+
+	out, err := mergeCaddyRoutes(own, live, "shop")
+	if err != nil {
+		t.Fatalf("mergeCaddyRoutes: %v", err)
+	}
+	var cfg CaddyConfig
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	server := cfg.Apps.HTTP.Servers.Minipaas
+	var dials []string
+	for _, r := range server.Routes {
+		dials = append(dials, r.Handle[0].Upstreams[0].Dial)
+	}
+	// Stale routes of the stack are dropped, routes of other stacks kept
+	// unless the env config routes the same host and path.
+	if want := []string{"shop_api:8080", "blog_web:80"}; !reflect.DeepEqual(dials, want) {
+		t.Fatalf("dials = %v, want %v", dials, want)
+	}
+	if !reflect.DeepEqual(server.Listen, []string{":443", ":8000"}) {
+		t.Fatalf("listen = %v", server.Listen)
+	}
+
+	for _, empty := range []string{"", "null\n"} {
+		if out, err := mergeCaddyRoutes(own, []byte(empty), "shop"); err != nil || string(out) != string(own) {
+			t.Fatalf("empty live config should keep the env config, got %s (%v)", out, err)
+		}
+	}
+}
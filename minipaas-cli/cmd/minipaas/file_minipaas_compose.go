@@ -3,8 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +18,10 @@ import (
 
 const appsFile = "compose.apps.yaml"
 
+// deployVersionRef is the tag of the images built by MiniPaaS, resolved from
+// deploy.version when deploying.
+const deployVersionRef = "${MINIPAAS_DEPLOY_VERSION}"
+
 // loadComposeFile loads a single compose file at the provided path, applying
 // the same normalization as loadProject (e.g. removing default networks).
 func loadComposeFile(file string) (*types.Project, string, error) {
@@ -23,7 +31,7 @@ func loadComposeFile(file string) (*types.Project, string, error) {
 		[]string{file},
 		// Keep behavior consistent with loadProject regarding env resolution
 		cli.WithEnv([]string{"MINIPAAS_DEPLOY_VERSION=${MINIPAAS_DEPLOY_VERSION}"}),
-		cli.WithName("minipaas"),
+		cli.WithName(defaultStack),
 		cli.WithResolvedPaths(false),
 		cli.WithConsistency(false),
 		cli.WithoutEnvironmentResolution,
@@ -57,12 +65,13 @@ func loadComposeFile(file string) (*types.Project, string, error) {
 
 // saveComposeFile marshals and writes the project content to the given file path.
 func saveComposeFile(file string, project *types.Project) (string, error) {
+	unsetDerivedNames(project)
 	project.Name = ""
 	data, err := project.MarshalYAML()
 	if err != nil {
 		return file, err
 	}
-	return file, os.WriteFile(file, data, 0644)
+	return file, writeFile(file, data, 0644)
 }
 
 // composeFilesForEnv returns the list of compose files to consider for an env,
@@ -99,6 +108,13 @@ func composeFilesForEnv(env string, cfg Config) []string {
 	return ordered
 }
 
+// deployComposeFiles returns the compose files a deployment of env is made
+// of: the files of cfg.Project.Files followed by the env apps file. The slice
+// is a copy, so callers may append to it.
+func deployComposeFiles(env string, cfg Config) []string {
+	return append(slices.Clone(cfg.Project.Files), filepath.Join(env, appsFile))
+}
+
 // groupServicesByComposeFile returns a mapping from compose file path to the
 // subset of services that belong in that file, plus a list of any services that
 // could not be found in any provided compose file.
@@ -128,14 +144,14 @@ func groupServicesByComposeFile(files []string, services []string) (map[string][
 	return svcPerFile, missing
 }
 
-func createDeployService(svc types.ServiceConfig, requiresBuild bool) types.ServiceConfig {
+func createDeployService(svc types.ServiceConfig, requiresBuild bool, registry string) types.ServiceConfig {
 	srv := types.ServiceConfig{
 		Networks: map[string]*types.ServiceNetworkConfig{
-			"minipaas_network": {},
+			sharedNetwork: {},
 		},
 	}
 	if requiresBuild {
-		srv.Image = buildCommonImage(svc.Image)
+		srv.Image = buildCommonImage(svc.Image, registry)
 	}
 	return srv
 }
@@ -143,7 +159,7 @@ func createDeployService(svc types.ServiceConfig, requiresBuild bool) types.Serv
 func composeEnsureDeploy(project *types.Project, serviceName string) {
 	svc, ok := project.Services[serviceName]
 	if !ok {
-		svc = createDeployService(project.Services[serviceName], false)
+		svc = createDeployService(project.Services[serviceName], false, "")
 		project.Services[serviceName] = svc
 	}
 }
@@ -156,7 +172,7 @@ func loadProject(env string) (*types.Project, string, error) {
 		[]string{fn},
 		// dirty trick. MINIPAAS_DEPLOY_VERSION needs to be defined
 		cli.WithEnv([]string{"MINIPAAS_DEPLOY_VERSION=${MINIPAAS_DEPLOY_VERSION}"}),
-		cli.WithName("minipaas"),
+		cli.WithName(defaultStack),
 		cli.WithResolvedPaths(false),
 		cli.WithConsistency(false),
 		cli.WithoutEnvironmentResolution,
@@ -191,26 +207,103 @@ func loadProject(env string) (*types.Project, string, error) {
 func saveProject(env string, project *types.Project) (string, error) {
 	fn := filepath.Join(env, appsFile)
 
+	unsetDerivedNames(project)
 	project.Name = ""
 	data, err := project.MarshalYAML()
 	if err != nil {
 		return fn, err
 	}
-	return fn, os.WriteFile(fn, data, 0644)
+	return fn, writeFile(fn, data, 0644)
 }
 
-func buildCommonImage(image string) string {
-	version := "${MINIPAAS_DEPLOY_VERSION}"
+// buildCommonImage names the image MiniPaaS builds for a service: unqualified
+// images are pushed to registry, all are tagged with the deploy version.
+func buildCommonImage(image, registry string) string {
+	image = imageRepository(image)
+	if !strings.Contains(image, "/") {
+		image = registry + "/" + image
+	}
 
-	if idx := strings.LastIndex(image, ":"); idx != -1 {
+	return image + ":" + deployVersionRef
+}
+
+// imageRepository strips the tag and digest from an image reference.
+func imageRepository(image string) string {
+	if idx := strings.Index(image, "@"); idx != -1 {
+		image = image[:idx]
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
 		image = image[:idx]
 	}
+	return image
+}
 
-	if !strings.Contains(image, "/") {
-		image = "registry:5000/" + image
+// imageRetag is an image of compose.apps.yaml written for another registry
+// than the configured one.
+type imageRetag struct {
+	Service string
+	From    string
+	To      string
+}
+
+// registryRetags returns the images of apps that code init generated for a
+// service built from source, but for another registry. Images set by hand
+// are left alone.
+func registryRetags(apps, source *types.Project, registry string) []imageRetag {
+	var retags []imageRetag
+	for _, name := range slices.Sorted(maps.Keys(apps.Services)) {
+		svc := apps.Services[name]
+		src, ok := source.Services[name]
+		if !ok || src.Build == nil || svc.Image == "" {
+			continue
+		}
+		repo := imageRepository(src.Image)
+		if strings.Contains(repo, "/") || !strings.HasSuffix(svc.Image, "/"+repo+":"+deployVersionRef) {
+			continue
+		}
+		if want := buildCommonImage(src.Image, registry); svc.Image != want {
+			retags = append(retags, imageRetag{Service: name, From: svc.Image, To: want})
+		}
+	}
+	return retags
+}
+
+// loadSourceProject loads the compose files of the env, without the
+// compose.apps.yaml generated from them.
+func loadSourceProject(env string, cfg Config) (*types.Project, error) {
+	apps := filepath.Clean(filepath.Join(env, appsFile))
+	var files []string
+	for _, f := range cfg.Project.Files {
+		if filepath.Clean(f) != apps {
+			files = append(files, f)
+		}
 	}
+	return composeLoadProject(files)
+}
 
-	return image + ":" + version
+// envRegistryRetags compares compose.apps.yaml of env with the registry
+// configured in cfg.
+func envRegistryRetags(env string, cfg Config) ([]imageRetag, error) {
+	apps, fn, err := loadProject(env)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	source, err := loadSourceProject(env, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return registryRetags(apps, source, cfg.RegistryName()), nil
+}
+
+// applyRegistryRetags points the services of a deploy project to their image
+// in the configured registry, for the given deploy version.
+func applyRegistryRetags(project *types.Project, retags []imageRetag, version string) {
+	for _, r := range retags {
+		if svc, ok := project.Services[r.Service]; ok {
+			svc.Image = strings.ReplaceAll(r.To, deployVersionRef, version)
+			project.Services[r.Service] = svc
+		}
+	}
 }
 
 func buildDeployProject() *types.Project {
@@ -373,7 +466,97 @@ func addComposeCronDeploy(project *types.Project, serviceName string, cron strin
 	return nil
 }
 
-func addComposeConfig(project *types.Project, config, name string, services []string) error {
+// serviceMount is how one service mounts a secret/config. Empty fields keep
+// the defaults: the base name as target and the daemon's ownership and mode.
+type serviceMount struct {
+	Service string
+	Target  string
+	UID     string
+	GID     string
+	Mode    *types.FileMode
+}
+
+func (m serviceMount) targetOr(name string) string {
+	if m.Target != "" {
+		return m.Target
+	}
+	return name
+}
+
+// apply sets the requested ownership and mode on ref, keeping its current
+// values for the fields left empty.
+func (m serviceMount) apply(ref types.FileReferenceConfig) types.FileReferenceConfig {
+	if m.UID != "" {
+		ref.UID = m.UID
+	}
+	if m.GID != "" {
+		ref.GID = m.GID
+	}
+	if m.Mode != nil {
+		mode := *m.Mode
+		ref.Mode = &mode
+	}
+	return ref
+}
+
+// parseServiceMounts turns --for values of the form service[:target] into
+// mounts. defaults carries the --target, --uid, --gid and --mode flags; a
+// per-service target takes precedence over --target.
+func parseServiceMounts(specs []string, defaults serviceMount) ([]serviceMount, error) {
+	var mounts []serviceMount
+	for _, spec := range specs {
+		m := defaults
+		service, target, hasTarget := strings.Cut(spec, ":")
+		if service == "" || (hasTarget && target == "") {
+			return nil, fmt.Errorf("invalid --for %q: use service or service:target", spec)
+		}
+		m.Service = service
+		if hasTarget {
+			m.Target = target
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// parseFileMode parses an octal file mode such as 0400. An empty string means
+// no mode.
+func parseFileMode(s string) (*types.FileMode, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || v > 0777 {
+		return nil, fmt.Errorf("invalid mode %q: use an octal value such as 0400", s)
+	}
+	mode := types.FileMode(v)
+	return &mode, nil
+}
+
+func mountServices(mounts []serviceMount) []string {
+	var services []string
+	for _, m := range mounts {
+		services = append(services, m.Service)
+	}
+	return services
+}
+
+// mountsForServices returns the mounts that belong to one of services.
+func mountsForServices(mounts []serviceMount, services []string) []serviceMount {
+	wanted := map[string]bool{}
+	for _, svc := range services {
+		wanted[svc] = true
+	}
+	var selected []serviceMount
+	for _, m := range mounts {
+		if wanted[m.Service] {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+func addComposeConfig(project *types.Project, config, name string, mounts []serviceMount) error {
 	if project.Configs == nil {
 		project.Configs = make(map[string]types.ConfigObjConfig)
 	}
@@ -382,35 +565,59 @@ func addComposeConfig(project *types.Project, config, name string, services []st
 		External: true,
 	}
 
-	for _, svcName := range services {
-		svc, ok := project.Services[svcName]
+	var replaced []string
+	for _, m := range mounts {
+		svc, ok := project.Services[m.Service]
 		if !ok {
-			return fmt.Errorf("service %s not found in project", svcName)
+			return fmt.Errorf("service %s not found in project", m.Service)
 		}
 		if svc.Configs == nil {
 			svc.Configs = []types.ServiceConfigObjConfig{}
 		}
 
+		// Swap older versions mounted at the same target instead of adding
+		// a second source for it.
+		targetName := m.targetOr(name)
+		target := configMountPath(targetName, config)
 		exists := false
+		seen := map[string]bool{}
+		var kept []types.ServiceConfigObjConfig
 		for _, sc := range svc.Configs {
-			if sc.Source == config {
+			if sc.Source != config && configMountPath(sc.Target, sc.Source) == target {
+				replaced = append(replaced, sc.Source)
+				sc.Source = config
+			}
+			key := sc.Source + "\x00" + configMountPath(sc.Target, sc.Source)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if sc.Source == config && configMountPath(sc.Target, sc.Source) == target {
 				exists = true
-				break
+				sc = types.ServiceConfigObjConfig(m.apply(types.FileReferenceConfig(sc)))
 			}
+			kept = append(kept, sc)
 		}
+		svc.Configs = kept
 		if !exists {
-			svc.Configs = append(svc.Configs, types.ServiceConfigObjConfig{
+			svc.Configs = append(svc.Configs, types.ServiceConfigObjConfig(m.apply(types.FileReferenceConfig{
 				Source: config,
-				Target: name,
-			})
+				Target: targetName,
+			})))
+		}
+		project.Services[m.Service] = svc
+	}
+
+	for _, old := range replaced {
+		if !composeConfigReferenced(project, old) {
+			delete(project.Configs, old)
 		}
-		project.Services[svcName] = svc
 	}
 
 	return nil
 }
 
-func addComposeSecret(project *types.Project, secret, name string, services []string) error {
+func addComposeSecret(project *types.Project, secret, name string, mounts []serviceMount) error {
 	if project.Secrets == nil {
 		project.Secrets = make(map[string]types.SecretConfig)
 	}
@@ -419,34 +626,106 @@ func addComposeSecret(project *types.Project, secret, name string, services []st
 		External: true,
 	}
 
-	for _, svcName := range services {
-		svc, ok := project.Services[svcName]
+	var replaced []string
+	for _, m := range mounts {
+		svc, ok := project.Services[m.Service]
 		if !ok {
-			return fmt.Errorf("service %s not found in project", svcName)
+			return fmt.Errorf("service %s not found in project", m.Service)
 		}
 		if svc.Secrets == nil {
 			svc.Secrets = []types.ServiceSecretConfig{}
 		}
 
+		// Swap older versions mounted at the same target instead of adding
+		// a second source for it.
+		targetName := m.targetOr(name)
+		target := secretMountPath(targetName, secret)
 		exists := false
+		seen := map[string]bool{}
+		var kept []types.ServiceSecretConfig
 		for _, sc := range svc.Secrets {
-			if sc.Source == secret {
+			if sc.Source != secret && secretMountPath(sc.Target, sc.Source) == target {
+				replaced = append(replaced, sc.Source)
+				sc.Source = secret
+			}
+			key := sc.Source + "\x00" + secretMountPath(sc.Target, sc.Source)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if sc.Source == secret && secretMountPath(sc.Target, sc.Source) == target {
 				exists = true
-				break
+				sc = types.ServiceSecretConfig(m.apply(types.FileReferenceConfig(sc)))
 			}
+			kept = append(kept, sc)
 		}
+		svc.Secrets = kept
 		if !exists {
-			svc.Secrets = append(svc.Secrets, types.ServiceSecretConfig{
+			svc.Secrets = append(svc.Secrets, types.ServiceSecretConfig(m.apply(types.FileReferenceConfig{
 				Source: secret,
-				Target: name,
-			})
+				Target: targetName,
+			})))
+		}
+		project.Services[m.Service] = svc
+	}
+
+	for _, old := range replaced {
+		if !composeSecretReferenced(project, old) {
+			delete(project.Secrets, old)
 		}
-		project.Services[svcName] = svc
 	}
 
 	return nil
 }
 
+// secretMountPath resolves a service secret target to the absolute path it is
+// mounted at, defaulting to the source name under /run/secrets.
+func secretMountPath(target, source string) string {
+	if target == "" {
+		target = source
+	}
+	if strings.HasPrefix(target, "/") {
+		return target
+	}
+	return "/run/secrets/" + target
+}
+
+// configMountPath resolves a service config target to the absolute path it is
+// mounted at, defaulting to the source name at the filesystem root.
+func configMountPath(target, source string) string {
+	if target == "" {
+		target = source
+	}
+	if strings.HasPrefix(target, "/") {
+		return target
+	}
+	return "/" + target
+}
+
+// composeSecretReferenced reports whether any service still mounts secret.
+func composeSecretReferenced(project *types.Project, secret string) bool {
+	for _, svc := range project.Services {
+		for _, sc := range svc.Secrets {
+			if sc.Source == secret {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// composeConfigReferenced reports whether any service still mounts config.
+func composeConfigReferenced(project *types.Project, config string) bool {
+	for _, svc := range project.Services {
+		for _, sc := range svc.Configs {
+			if sc.Source == config {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func updateDeployFileRemoveConfig(env, configName string) (string, error) {
 	project, fn, err := loadProject(env)
 	if err != nil {
@@ -494,3 +773,623 @@ func removeConfigFromDeployProject(project *types.Project, configName string) bo
 	}
 	return changed
 }
+
+func removeSecretFromDeployProject(project *types.Project, secretName string) bool {
+	changed := false
+
+	if project.Secrets != nil {
+		if _, exists := project.Secrets[secretName]; exists {
+			delete(project.Secrets, secretName)
+			changed = true
+			fmt.Printf("✅ Removed secret %s from top-level secrets\n", secretName)
+		}
+	}
+
+	for svcName, svc := range project.Services {
+		if len(svc.Secrets) > 0 {
+			var filteredSecrets []types.ServiceSecretConfig
+			for _, sec := range svc.Secrets {
+				if sec.Source != secretName {
+					filteredSecrets = append(filteredSecrets, sec)
+				}
+			}
+			if len(filteredSecrets) != len(svc.Secrets) {
+				svc.Secrets = filteredSecrets
+				project.Services[svcName] = svc
+				changed = true
+				fmt.Printf("✅ Removed secret %s from service %s\n", secretName, svcName)
+			}
+		}
+	}
+	return changed
+}
+
+// composeSecretNames returns every secret name declared at top level or
+// referenced by a service.
+func composeSecretNames(project *types.Project) map[string]bool {
+	seen := map[string]bool{}
+	for name := range project.Secrets {
+		seen[name] = true
+	}
+	for _, svc := range project.Services {
+		for _, sec := range svc.Secrets {
+			seen[sec.Source] = true
+		}
+	}
+	return seen
+}
+
+// composeConfigNames is the config counterpart of composeSecretNames.
+func composeConfigNames(project *types.Project) map[string]bool {
+	seen := map[string]bool{}
+	for name := range project.Configs {
+		seen[name] = true
+	}
+	for _, svc := range project.Services {
+		for _, cfg := range svc.Configs {
+			seen[cfg.Source] = true
+		}
+	}
+	return seen
+}
+
+// composeSecretVersions returns the secret names known to project that are
+// baseName or one of its hashed versions.
+func composeSecretVersions(project *types.Project, baseName string) []string {
+	return sortedObjectVersions(composeSecretNames(project), baseName)
+}
+
+// composeConfigVersions is the config counterpart of composeSecretVersions.
+func composeConfigVersions(project *types.Project, baseName string) []string {
+	return sortedObjectVersions(composeConfigNames(project), baseName)
+}
+
+// composeFilesSecretNames collects the secret names of every compose file.
+// Unlike groupServicesByComposeFile, unreadable files are an error: callers
+// use the result to decide what is safe to delete.
+func composeFilesSecretNames(files []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		for name := range composeSecretNames(project) {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+// composeFilesConfigNames is the config counterpart of composeFilesSecretNames.
+func composeFilesConfigNames(files []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		for name := range composeConfigNames(project) {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+func sortedObjectVersions(seen map[string]bool, baseName string) []string {
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return filterObjectVersions(names, baseName)
+}
+
+// updateComposeFilesRemoveSecret removes every version of baseName from each
+// compose file, both top-level declarations and service references. Only
+// files that actually change are written back; their paths are returned.
+func updateComposeFilesRemoveSecret(files []string, baseName string) ([]string, error) {
+	var changedFiles []string
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return changedFiles, fmt.Errorf("load %s: %w", file, err)
+		}
+
+		changed := false
+		for _, name := range composeSecretVersions(project, baseName) {
+			if removeSecretFromDeployProject(project, name) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if _, err = saveComposeFile(file, project); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// updateComposeFilesRemoveConfig is the config counterpart of
+// updateComposeFilesRemoveSecret.
+func updateComposeFilesRemoveConfig(files []string, baseName string) ([]string, error) {
+	var changedFiles []string
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return changedFiles, fmt.Errorf("load %s: %w", file, err)
+		}
+
+		changed := false
+		for _, name := range composeConfigVersions(project, baseName) {
+			if removeConfigFromDeployProject(project, name) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if _, err = saveComposeFile(file, project); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// objectMount describes a service mounting a secret/config version.
+type objectMount struct {
+	File    string
+	Service string
+	Target  string
+}
+
+// composeFilesSecretMounts maps each secret name referenced by the compose
+// files to the services mounting it. Secrets declared at top level but not
+// mounted by any service are included with no mounts.
+func composeFilesSecretMounts(files []string) (map[string][]objectMount, error) {
+	mounts := map[string][]objectMount{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		for name := range project.Secrets {
+			if _, ok := mounts[name]; !ok {
+				mounts[name] = nil
+			}
+		}
+		for _, svcName := range project.ServiceNames() {
+			for _, sec := range project.Services[svcName].Secrets {
+				mounts[sec.Source] = append(mounts[sec.Source], objectMount{File: file, Service: svcName, Target: sec.Target})
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// composeFilesConfigMounts is the config counterpart of composeFilesSecretMounts.
+func composeFilesConfigMounts(files []string) (map[string][]objectMount, error) {
+	mounts := map[string][]objectMount{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		for name := range project.Configs {
+			if _, ok := mounts[name]; !ok {
+				mounts[name] = nil
+			}
+		}
+		for _, svcName := range project.ServiceNames() {
+			for _, cfg := range project.Services[svcName].Configs {
+				mounts[cfg.Source] = append(mounts[cfg.Source], objectMount{File: file, Service: svcName, Target: cfg.Target})
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// rotateComposeSecret points every service mount of a version of baseName to
+// newName, keeping target, uid, gid and mode. It returns the swapped mounts per
+// service and the previous versions no longer referenced by the project.
+func rotateComposeSecret(project *types.Project, baseName, newName string) (map[string][]types.ServiceSecretConfig, []string) {
+	swapped := map[string][]types.ServiceSecretConfig{}
+	previous := map[string]bool{}
+
+	for _, svcName := range project.ServiceNames() {
+		svc := project.Services[svcName]
+		changed := false
+		seen := map[string]bool{}
+		var kept []types.ServiceSecretConfig
+		for _, sc := range svc.Secrets {
+			if sc.Source != newName && objectNameMatches(sc.Source, baseName) {
+				previous[sc.Source] = true
+				sc.Source = newName
+				changed = true
+			}
+			key := sc.Source + "\x00" + secretMountPath(sc.Target, sc.Source)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, sc)
+		}
+		if changed {
+			for _, sc := range kept {
+				if sc.Source == newName {
+					swapped[svcName] = append(swapped[svcName], sc)
+				}
+			}
+			svc.Secrets = kept
+			project.Services[svcName] = svc
+		}
+	}
+
+	for name := range project.Secrets {
+		if name != newName && objectNameMatches(name, baseName) {
+			previous[name] = true
+		}
+	}
+	var old []string
+	for name := range previous {
+		if !composeSecretReferenced(project, name) {
+			delete(project.Secrets, name)
+			old = append(old, name)
+		}
+	}
+	if len(swapped) > 0 {
+		if project.Secrets == nil {
+			project.Secrets = make(map[string]types.SecretConfig)
+		}
+		project.Secrets[newName] = types.SecretConfig{External: true}
+	}
+	sort.Strings(old)
+	return swapped, old
+}
+
+// rotateComposeConfig is the config counterpart of rotateComposeSecret.
+func rotateComposeConfig(project *types.Project, baseName, newName string) (map[string][]types.ServiceConfigObjConfig, []string) {
+	swapped := map[string][]types.ServiceConfigObjConfig{}
+	previous := map[string]bool{}
+
+	for _, svcName := range project.ServiceNames() {
+		svc := project.Services[svcName]
+		changed := false
+		seen := map[string]bool{}
+		var kept []types.ServiceConfigObjConfig
+		for _, sc := range svc.Configs {
+			if sc.Source != newName && objectNameMatches(sc.Source, baseName) {
+				previous[sc.Source] = true
+				sc.Source = newName
+				changed = true
+			}
+			key := sc.Source + "\x00" + configMountPath(sc.Target, sc.Source)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, sc)
+		}
+		if changed {
+			for _, sc := range kept {
+				if sc.Source == newName {
+					swapped[svcName] = append(swapped[svcName], sc)
+				}
+			}
+			svc.Configs = kept
+			project.Services[svcName] = svc
+		}
+	}
+
+	for name := range project.Configs {
+		if name != newName && objectNameMatches(name, baseName) {
+			previous[name] = true
+		}
+	}
+	var old []string
+	for name := range previous {
+		if !composeConfigReferenced(project, name) {
+			delete(project.Configs, name)
+			old = append(old, name)
+		}
+	}
+	if len(swapped) > 0 {
+		if project.Configs == nil {
+			project.Configs = make(map[string]types.ConfigObjConfig)
+		}
+		project.Configs[newName] = types.ConfigObjConfig{External: true}
+	}
+	sort.Strings(old)
+	return swapped, old
+}
+
+// updateComposeFilesAddSecret attaches secret to each service in whichever
+// compose file owns it and returns the files that were written.
+func updateComposeFilesAddSecret(files []string, secret, name string, mounts []serviceMount) ([]string, error) {
+	svcPerFile, missing := groupServicesByComposeFile(files, mountServices(mounts))
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("services not found: %v", missing)
+	}
+
+	var changedFiles []string
+	for _, file := range files {
+		svcs, ok := svcPerFile[file]
+		if !ok {
+			continue
+		}
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return changedFiles, fmt.Errorf("load %s: %w", file, err)
+		}
+		if err = addComposeSecret(project, secret, name, mountsForServices(mounts, svcs)); err != nil {
+			return changedFiles, fmt.Errorf("update %s: %w", file, err)
+		}
+		if _, err = saveComposeFile(file, project); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// updateComposeFilesAddConfig is the config counterpart of
+// updateComposeFilesAddSecret.
+func updateComposeFilesAddConfig(files []string, config, name string, mounts []serviceMount) ([]string, error) {
+	svcPerFile, missing := groupServicesByComposeFile(files, mountServices(mounts))
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("services not found: %v", missing)
+	}
+
+	var changedFiles []string
+	for _, file := range files {
+		svcs, ok := svcPerFile[file]
+		if !ok {
+			continue
+		}
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return changedFiles, fmt.Errorf("load %s: %w", file, err)
+		}
+		if err = addComposeConfig(project, config, name, mountsForServices(mounts, svcs)); err != nil {
+			return changedFiles, fmt.Errorf("update %s: %w", file, err)
+		}
+		if _, err = saveComposeFile(file, project); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// addComposeEnvironment sets the given environment variables on each service,
+// replacing any previous value.
+func addComposeEnvironment(project *types.Project, vars map[string]string, services []string) error {
+	for _, svcName := range services {
+		svc, ok := project.Services[svcName]
+		if !ok {
+			return fmt.Errorf("service %s not found in project", svcName)
+		}
+		if svc.Environment == nil {
+			svc.Environment = types.MappingWithEquals{}
+		}
+		for key, value := range vars {
+			v := value
+			svc.Environment[key] = &v
+		}
+		project.Services[svcName] = svc
+	}
+	return nil
+}
+
+// updateComposeFilesAddEnvironment sets vars on each service in whichever
+// compose file owns it and returns the files that were written.
+func updateComposeFilesAddEnvironment(files []string, vars map[string]string, services []string) ([]string, error) {
+	svcPerFile, missing := groupServicesByComposeFile(files, services)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("services not found: %v", missing)
+	}
+
+	var changedFiles []string
+	for _, file := range files {
+		svcs, ok := svcPerFile[file]
+		if !ok {
+			continue
+		}
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return changedFiles, fmt.Errorf("load %s: %w", file, err)
+		}
+		if err = addComposeEnvironment(project, vars, svcs); err != nil {
+			return changedFiles, fmt.Errorf("update %s: %w", file, err)
+		}
+		if _, err = saveComposeFile(file, project); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// detachComposeSecret unmounts the secret name from services and returns the
+// sources that were removed. A base name matches all of its versions; a
+// hashed name only itself.
+func detachComposeSecret(project *types.Project, name string, services []string) ([]string, error) {
+	var removed []string
+	for _, svcName := range services {
+		svc, ok := project.Services[svcName]
+		if !ok {
+			return removed, fmt.Errorf("service %s not found in project", svcName)
+		}
+		var kept []types.ServiceSecretConfig
+		for _, sc := range svc.Secrets {
+			if sc.Source == name || objectBaseName(sc.Source) == name {
+				removed = append(removed, sc.Source)
+				fmt.Printf("✅ Removed secret %s from service %s\n", sc.Source, svcName)
+				continue
+			}
+			kept = append(kept, sc)
+		}
+		svc.Secrets = kept
+		project.Services[svcName] = svc
+	}
+	return removed, nil
+}
+
+// updateComposeFilesDetachSecret unmounts name from services in whichever
+// compose file owns each of them. Top-level declarations are dropped only
+// once no service in any of the files mounts the secret anymore.
+func updateComposeFilesDetachSecret(files []string, name string, services []string) ([]string, error) {
+	svcPerFile, missing := groupServicesByComposeFile(files, services)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("services not found: %v", missing)
+	}
+
+	projects := map[string]*types.Project{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		projects[file] = project
+	}
+
+	changed := map[string]bool{}
+	detached := map[string]bool{}
+	for file, svcs := range svcPerFile {
+		removed, err := detachComposeSecret(projects[file], name, svcs)
+		if err != nil {
+			return nil, fmt.Errorf("update %s: %w", file, err)
+		}
+		for _, source := range removed {
+			detached[source] = true
+			changed[file] = true
+		}
+	}
+
+	for source := range detached {
+		referenced := false
+		for _, project := range projects {
+			if composeSecretReferenced(project, source) {
+				referenced = true
+				break
+			}
+		}
+		if referenced {
+			continue
+		}
+		for _, file := range files {
+			if _, ok := projects[file].Secrets[source]; ok {
+				delete(projects[file].Secrets, source)
+				changed[file] = true
+				fmt.Printf("✅ Removed secret %s from top-level secrets\n", source)
+			}
+		}
+	}
+
+	var changedFiles []string
+	for _, file := range files {
+		if !changed[file] {
+			continue
+		}
+		if _, err := saveComposeFile(file, projects[file]); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
+
+// detachComposeConfig unmounts the config name from services and returns the
+// sources that were removed. A base name matches all of its versions; a
+// hashed name only itself.
+func detachComposeConfig(project *types.Project, name string, services []string) ([]string, error) {
+	var removed []string
+	for _, svcName := range services {
+		svc, ok := project.Services[svcName]
+		if !ok {
+			return removed, fmt.Errorf("service %s not found in project", svcName)
+		}
+		var kept []types.ServiceConfigObjConfig
+		for _, sc := range svc.Configs {
+			if sc.Source == name || objectBaseName(sc.Source) == name {
+				removed = append(removed, sc.Source)
+				fmt.Printf("✅ Removed config %s from service %s\n", sc.Source, svcName)
+				continue
+			}
+			kept = append(kept, sc)
+		}
+		svc.Configs = kept
+		project.Services[svcName] = svc
+	}
+	return removed, nil
+}
+
+// updateComposeFilesDetachConfig unmounts name from services in whichever
+// compose file owns each of them. Top-level declarations are dropped only
+// once no service in any of the files mounts the config anymore.
+func updateComposeFilesDetachConfig(files []string, name string, services []string) ([]string, error) {
+	svcPerFile, missing := groupServicesByComposeFile(files, services)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("services not found: %v", missing)
+	}
+
+	projects := map[string]*types.Project{}
+	for _, file := range files {
+		project, _, err := loadComposeFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		projects[file] = project
+	}
+
+	changed := map[string]bool{}
+	detached := map[string]bool{}
+	for file, svcs := range svcPerFile {
+		removed, err := detachComposeConfig(projects[file], name, svcs)
+		if err != nil {
+			return nil, fmt.Errorf("update %s: %w", file, err)
+		}
+		for _, source := range removed {
+			detached[source] = true
+			changed[file] = true
+		}
+	}
+
+	for source := range detached {
+		referenced := false
+		for _, project := range projects {
+			if composeConfigReferenced(project, source) {
+				referenced = true
+				break
+			}
+		}
+		if referenced {
+			continue
+		}
+		for _, file := range files {
+			if _, ok := projects[file].Configs[source]; ok {
+				delete(projects[file].Configs, source)
+				changed[file] = true
+				fmt.Printf("✅ Removed config %s from top-level configs\n", source)
+			}
+		}
+	}
+
+	var changedFiles []string
+	for _, file := range files {
+		if !changed[file] {
+			continue
+		}
+		if _, err := saveComposeFile(file, projects[file]); err != nil {
+			return changedFiles, fmt.Errorf("write %s: %w", file, err)
+		}
+		changedFiles = append(changedFiles, file)
+	}
+	return changedFiles, nil
+}
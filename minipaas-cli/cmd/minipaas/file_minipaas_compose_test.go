@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/compose-spec/compose-go/v2/types"
@@ -32,6 +33,22 @@ func TestComposeFilesForEnv_RelativePaths(t *testing.T) {
 	}
 }
 
+func TestDeployComposeFiles(t *testing.T) {
+	files := make([]string, 1, 4)
+	files[0] = "compose.yaml"
+	cfg := Config{Project: ProjectConfig{Files: files}}
+
+	dev := deployComposeFiles("dev", cfg)
+	prod := deployComposeFiles("prod", cfg)
+	if len(dev) != 2 || dev[1] != filepath.Join("dev", appsFile) || prod[1] != filepath.Join("prod", appsFile) {
+		t.Fatalf("unexpected compose files: %#v / %#v", dev, prod)
+	}
+	// the config keeps its files, even when its slice has spare capacity
+	if len(cfg.Project.Files) != 1 || files[:2][1] != "" {
+		t.Fatalf("config files modified: %#v", files[:2])
+	}
+}
+
 func TestGroupServicesByComposeFile(t *testing.T) {
 	dir := t.TempDir()
 	f1 := filepath.Join(dir, "a.yml")
@@ -160,14 +177,14 @@ func TestBuildDeployProject(t *testing.T) {
 
 func TestCreateDeployService_RequiresBuild(t *testing.T) {
 	src := types.ServiceConfig{Image: "repo/app:1"}
-	out := createDeployService(src, true)
-	if out.Image != buildCommonImage(src.Image) {
+	out := createDeployService(src, true, defaultRegistry)
+	if out.Image != buildCommonImage(src.Image, defaultRegistry) {
 		t.Fatalf("image not rewritten: %q", out.Image)
 	}
 	if _, ok := out.Networks["minipaas_network"]; !ok {
 		t.Fatalf("network not set")
 	}
-	out2 := createDeployService(src, false)
+	out2 := createDeployService(src, false, defaultRegistry)
 	if out2.Image != "" {
 		t.Fatalf("image should be empty when not requiring build: %q", out2.Image)
 	}
@@ -258,20 +275,20 @@ func TestAddComposeSecretAndConfig_NoDupes(t *testing.T) {
 	p := &types.Project{Services: make(types.Services)}
 	p.Services["api"] = types.ServiceConfig{Image: "x"}
 
-	if err := addComposeSecret(p, "secret-hash", "env", []string{"api"}); err != nil {
+	if err := addComposeSecret(p, "secret-hash", "env", mountsFor("api")); err != nil {
 		t.Fatalf("add secret: %v", err)
 	}
-	if err := addComposeSecret(p, "secret-hash", "env", []string{"api"}); err != nil {
+	if err := addComposeSecret(p, "secret-hash", "env", mountsFor("api")); err != nil {
 		t.Fatalf("add secret twice: %v", err)
 	}
 	if len(p.Secrets) != 1 || len(p.Services["api"].Secrets) != 1 {
 		t.Fatalf("secret dupes detected: secrets=%d svcRefs=%d", len(p.Secrets), len(p.Services["api"].Secrets))
 	}
 
-	if err := addComposeConfig(p, "cfg-hash", "app.conf", []string{"api"}); err != nil {
+	if err := addComposeConfig(p, "cfg-hash", "app.conf", mountsFor("api")); err != nil {
 		t.Fatalf("add config: %v", err)
 	}
-	if err := addComposeConfig(p, "cfg-hash", "app.conf", []string{"api"}); err != nil {
+	if err := addComposeConfig(p, "cfg-hash", "app.conf", mountsFor("api")); err != nil {
 		t.Fatalf("add config twice: %v", err)
 	}
 	if len(p.Configs) != 1 || len(p.Services["api"].Configs) != 1 {
@@ -281,14 +298,14 @@ func TestAddComposeSecretAndConfig_NoDupes(t *testing.T) {
 
 func TestAddComposeSecretMissingService(t *testing.T) {
 	p := &types.Project{Services: make(types.Services)}
-	if err := addComposeSecret(p, "sec", "file", []string{"missing"}); err == nil {
+	if err := addComposeSecret(p, "sec", "file", mountsFor("missing")); err == nil {
 		t.Fatalf("expected error for missing service")
 	}
 }
 
 func TestAddComposeConfigMissingService(t *testing.T) {
 	p := &types.Project{Services: make(types.Services)}
-	if err := addComposeConfig(p, "cfg", "file", []string{"missing"}); err == nil {
+	if err := addComposeConfig(p, "cfg", "file", mountsFor("missing")); err == nil {
 		t.Fatalf("expected error for missing service")
 	}
 }
@@ -320,18 +337,536 @@ func TestRemoveConfigFromDeployProject(t *testing.T) {
 
 func TestBuildCommonImage(t *testing.T) {
 	tests := []struct {
-		in   string
-		want string
+		in       string
+		registry string
+		want     string
 	}{
-		{"myapp:latest", "registry:5000/myapp:${MINIPAAS_DEPLOY_VERSION}"},
-		{"myapp", "registry:5000/myapp:${MINIPAAS_DEPLOY_VERSION}"},
-		{"repo/myapp:1.2.3", "repo/myapp:${MINIPAAS_DEPLOY_VERSION}"},
-		{"registry:5001/repo/myapp:abc", "registry:5001/repo/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"myapp:latest", "registry:5000", "registry:5000/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"myapp", "registry:5000", "registry:5000/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"myapp", "ghcr.io/acme", "ghcr.io/acme/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"repo/myapp:1.2.3", "ghcr.io/acme", "repo/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"registry:5001/repo/myapp:abc", "registry:5000", "registry:5001/repo/myapp:${MINIPAAS_DEPLOY_VERSION}"},
+		{"registry:5001/repo/myapp", "registry:5000", "registry:5001/repo/myapp:${MINIPAAS_DEPLOY_VERSION}"},
 	}
 	for _, tt := range tests {
-		got := buildCommonImage(tt.in)
+		got := buildCommonImage(tt.in, tt.registry)
 		if got != tt.want {
 			t.Fatalf("buildCommonImage(%q)=%q want %q", tt.in, got, tt.want)
 		}
 	}
 }
+
+func TestRemoveSecretFromDeployProject(t *testing.T) {
+	p := &types.Project{Services: make(types.Services), Secrets: map[string]types.SecretConfig{
+		"secA": {External: true},
+		"secB": {External: true},
+	}}
+	p.Services["api"] = types.ServiceConfig{
+		Secrets: []types.ServiceSecretConfig{
+			{Source: "secA", Target: "a"},
+			{Source: "secB", Target: "b"},
+		},
+	}
+
+	if !removeSecretFromDeployProject(p, "secA") {
+		t.Fatalf("expected changes true")
+	}
+	if _, ok := p.Secrets["secA"]; ok {
+		t.Fatalf("secA should be removed from top-level")
+	}
+	if len(p.Services["api"].Secrets) != 1 || p.Services["api"].Secrets[0].Source != "secB" {
+		t.Fatalf("service secrets not filtered: %#v", p.Services["api"].Secrets)
+	}
+	if removeSecretFromDeployProject(p, "secA") {
+		t.Fatalf("expected no changes on second removal")
+	}
+}
+
+func TestUpdateComposeFilesRemoveSecret_AllVersionsAllFiles(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	pg := filepath.Join(dir, "compose.postgres.yml")
+	other := filepath.Join(dir, "compose.caddy.yml")
+
+	appsContent := "services:\n  api:\n    image: x\n    secrets:\n      - source: db.0123abcd\n        target: db\n      - source: db.267da420\n        target: db\n      - source: db_user.267da420\n        target: db_user\nsecrets:\n  db.0123abcd:\n    external: true\n  db.267da420:\n    external: true\n  db_user.267da420:\n    external: true\n"
+	pgContent := "services:\n  postgres:\n    image: postgres\n    secrets:\n      - source: db.267da420\n        target: db\nsecrets:\n  db.267da420:\n    external: true\n"
+	otherContent := "services:\n  caddy:\n    image: caddy\n"
+	for fn, content := range map[string]string{apps: appsContent, pg: pgContent, other: otherContent} {
+		if err := os.WriteFile(fn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := updateComposeFilesRemoveSecret([]string{apps, pg, other}, "db")
+	if err != nil {
+		t.Fatalf("updateComposeFilesRemoveSecret: %v", err)
+	}
+	if len(changed) != 2 || changed[0] != apps || changed[1] != pg {
+		t.Fatalf("changed files mismatch: %#v", changed)
+	}
+
+	p, _, err := loadComposeFile(apps)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(p.Secrets) != 1 {
+		t.Fatalf("only db_user should remain at top level: %#v", p.Secrets)
+	}
+	if secs := p.Services["api"].Secrets; len(secs) != 1 || secs[0].Source != "db_user.267da420" {
+		t.Fatalf("api secrets mismatch: %#v", secs)
+	}
+
+	p, _, err = loadComposeFile(pg)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(p.Secrets) != 0 || len(p.Services["postgres"].Secrets) != 0 {
+		t.Fatalf("postgres file not cleaned: %#v %#v", p.Secrets, p.Services["postgres"].Secrets)
+	}
+}
+
+func TestUpdateComposeFilesRemoveConfig(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	content := "services:\n  api:\n    image: x\n    configs:\n      - source: app.conf.267da420\n        target: app.conf\nconfigs:\n  app.conf.267da420:\n    external: true\n"
+	if err := os.WriteFile(apps, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := updateComposeFilesRemoveConfig([]string{apps}, "app.conf")
+	if err != nil {
+		t.Fatalf("updateComposeFilesRemoveConfig: %v", err)
+	}
+	if len(changed) != 1 {
+		t.Fatalf("expected one changed file: %#v", changed)
+	}
+	p, _, err := loadComposeFile(apps)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(p.Configs) != 0 || len(p.Services["api"].Configs) != 0 {
+		t.Fatalf("config not removed: %#v %#v", p.Configs, p.Services["api"].Configs)
+	}
+}
+
+func TestComposeFilesSecretNames(t *testing.T) {
+	dir := t.TempDir()
+	f1 := filepath.Join(dir, "a.yml")
+	f2 := filepath.Join(dir, "b.yml")
+	if err := os.WriteFile(f1, []byte("services:\n  api:\n    image: x\n    secrets:\n      - source: db.267da420\n        target: db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f2, []byte("services:\n  web:\n    image: y\nsecrets:\n  key.0123abcd:\n    external: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := composeFilesSecretNames([]string{f1, f2})
+	if err != nil {
+		t.Fatalf("composeFilesSecretNames: %v", err)
+	}
+	if len(names) != 2 || !names["db.267da420"] || !names["key.0123abcd"] {
+		t.Fatalf("names mismatch: %#v", names)
+	}
+
+	if _, err = composeFilesSecretNames([]string{filepath.Join(dir, "missing.yml")}); err == nil {
+		t.Fatalf("expected error for unreadable compose file")
+	}
+}
+
+func TestComposeFilesSecretMounts(t *testing.T) {
+	dir := t.TempDir()
+	f1 := filepath.Join(dir, "a.yml")
+	content := "services:\n  api:\n    image: x\n    secrets:\n      - source: db.267da420\n        target: db\n  worker:\n    image: x\n    secrets:\n      - source: db.267da420\nsecrets:\n  db.267da420:\n    external: true\n  unused.0123abcd:\n    external: true\n"
+	if err := os.WriteFile(f1, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts, err := composeFilesSecretMounts([]string{f1})
+	if err != nil {
+		t.Fatalf("composeFilesSecretMounts: %v", err)
+	}
+	db := mounts["db.267da420"]
+	if len(db) != 2 || db[0].Service != "api" || db[0].Target != "db" || db[1].Service != "worker" || db[1].Target != "/run/secrets/db.267da420" {
+		t.Fatalf("db mounts mismatch: %#v", db)
+	}
+	if m, ok := mounts["unused.0123abcd"]; !ok || len(m) != 0 {
+		t.Fatalf("declared-only secret should be listed without mounts: %#v", mounts)
+	}
+}
+
+func TestAddComposeSecret_ReplacesSameTarget(t *testing.T) {
+	p := &types.Project{Services: make(types.Services)}
+	p.Services["api"] = types.ServiceConfig{Image: "x"}
+	p.Services["worker"] = types.ServiceConfig{Image: "x"}
+
+	if err := addComposeSecret(p, "db.0123abcd", "db", mountsFor("api", "worker")); err != nil {
+		t.Fatalf("add secret: %v", err)
+	}
+	if err := addComposeSecret(p, "db.267da420", "db", mountsFor("api")); err != nil {
+		t.Fatalf("add new version: %v", err)
+	}
+
+	api := p.Services["api"].Secrets
+	if len(api) != 1 || api[0].Source != "db.267da420" || api[0].Target != "db" {
+		t.Fatalf("api secret not swapped in place: %#v", api)
+	}
+	// worker still mounts the old version, so the declaration must stay
+	if _, ok := p.Secrets["db.0123abcd"]; !ok {
+		t.Fatalf("old version still referenced by worker must stay declared: %#v", p.Secrets)
+	}
+
+	if err := addComposeSecret(p, "db.267da420", "db", mountsFor("worker")); err != nil {
+		t.Fatalf("add new version to worker: %v", err)
+	}
+	if _, ok := p.Secrets["db.0123abcd"]; ok {
+		t.Fatalf("unreferenced old version should be dropped: %#v", p.Secrets)
+	}
+}
+
+func TestAddComposeConfig_ReplacesSameTarget(t *testing.T) {
+	p := &types.Project{Services: make(types.Services)}
+	p.Services["api"] = types.ServiceConfig{
+		Image:   "x",
+		Configs: []types.ServiceConfigObjConfig{{Source: "app.conf.0123abcd", Target: "/app.conf"}},
+	}
+	p.Configs = map[string]types.ConfigObjConfig{"app.conf.0123abcd": {External: true}}
+
+	if err := addComposeConfig(p, "app.conf.267da420", "app.conf", mountsFor("api")); err != nil {
+		t.Fatalf("add config: %v", err)
+	}
+	cfgs := p.Services["api"].Configs
+	if len(cfgs) != 1 || cfgs[0].Source != "app.conf.267da420" || cfgs[0].Target != "/app.conf" {
+		t.Fatalf("config not swapped in place: %#v", cfgs)
+	}
+	if len(p.Configs) != 1 {
+		t.Fatalf("old config declaration should be dropped: %#v", p.Configs)
+	}
+}
+
+func TestRotateComposeSecret(t *testing.T) {
+	mode := types.FileMode(0400)
+	p := &types.Project{Services: make(types.Services), Secrets: map[string]types.SecretConfig{
+		"db.0123abcd":  {External: true},
+		"db.aaaaaaaa":  {External: true},
+		"key.bbbbbbbb": {External: true},
+	}}
+	p.Services["api"] = types.ServiceConfig{Secrets: []types.ServiceSecretConfig{
+		{Source: "db.0123abcd", Target: "/etc/app/db", UID: "1000", Mode: &mode},
+		{Source: "key.bbbbbbbb", Target: "key"},
+	}}
+	p.Services["worker"] = types.ServiceConfig{Secrets: []types.ServiceSecretConfig{
+		{Source: "db.0123abcd", Target: "db"},
+		{Source: "db.aaaaaaaa", Target: "db"},
+	}}
+	p.Services["web"] = types.ServiceConfig{Image: "x"}
+
+	swapped, old := rotateComposeSecret(p, "db", "db.267da420")
+
+	if len(swapped) != 2 {
+		t.Fatalf("expected api and worker swapped: %#v", swapped)
+	}
+	api := p.Services["api"].Secrets
+	if api[0].Source != "db.267da420" || api[0].Target != "/etc/app/db" || api[0].UID != "1000" || api[0].Mode == nil || *api[0].Mode != 0400 {
+		t.Fatalf("api mount options not preserved: %#v", api[0])
+	}
+	if api[1].Source != "key.bbbbbbbb" {
+		t.Fatalf("unrelated secret touched: %#v", api[1])
+	}
+	if worker := p.Services["worker"].Secrets; len(worker) != 1 || worker[0].Source != "db.267da420" {
+		t.Fatalf("worker duplicates not collapsed: %#v", worker)
+	}
+	if !reflect.DeepEqual(old, []string{"db.0123abcd", "db.aaaaaaaa"}) {
+		t.Fatalf("previous versions mismatch: %#v", old)
+	}
+	if _, ok := p.Secrets["db.267da420"]; !ok || len(p.Secrets) != 2 {
+		t.Fatalf("top-level secrets mismatch: %#v", p.Secrets)
+	}
+}
+
+func TestRotateComposeConfig_NothingToDo(t *testing.T) {
+	p := &types.Project{Services: make(types.Services)}
+	p.Services["api"] = types.ServiceConfig{Configs: []types.ServiceConfigObjConfig{{Source: "app.conf.267da420", Target: "/app.conf"}}}
+
+	swapped, old := rotateComposeConfig(p, "app.conf", "app.conf.267da420")
+	if len(swapped) != 0 || len(old) != 0 {
+		t.Fatalf("expected no changes: %#v %#v", swapped, old)
+	}
+}
+
+func TestUpdateComposeFilesAddSecret(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	pg := filepath.Join(dir, "compose.postgres.yml")
+	if err := os.WriteFile(apps, []byte("services:\n  api:\n    image: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pg, []byte("services:\n  postgres:\n    image: postgres\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := updateComposeFilesAddSecret([]string{apps, pg}, "db.267da420", "db", mountsFor("postgres", "api"))
+	if err != nil {
+		t.Fatalf("updateComposeFilesAddSecret: %v", err)
+	}
+	if len(changed) != 2 || changed[0] != apps || changed[1] != pg {
+		t.Fatalf("changed files mismatch: %#v", changed)
+	}
+	p, _, err := loadComposeFile(pg)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if secs := p.Services["postgres"].Secrets; len(secs) != 1 || secs[0].Source != "db.267da420" {
+		t.Fatalf("postgres secret missing: %#v", secs)
+	}
+
+	if _, err = updateComposeFilesAddSecret([]string{apps}, "db.267da420", "db", mountsFor("missing")); err == nil {
+		t.Fatalf("expected error for missing service")
+	}
+}
+
+func TestUpdateComposeFilesAddEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	if err := os.WriteFile(apps, []byte("services:\n  api:\n    image: x\n    environment:\n      DB_PASSWORD_FILE: /old\n      LOG_LEVEL: info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]string{"DB_PASSWORD_FILE": "/run/secrets/db_password"}
+	changed, err := updateComposeFilesAddEnvironment([]string{apps}, vars, []string{"api"})
+	if err != nil {
+		t.Fatalf("updateComposeFilesAddEnvironment: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != apps {
+		t.Fatalf("changed files mismatch: %#v", changed)
+	}
+	p, _, err := loadComposeFile(apps)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	env := p.Services["api"].Environment
+	if v := env["DB_PASSWORD_FILE"]; v == nil || *v != "/run/secrets/db_password" {
+		t.Fatalf("DB_PASSWORD_FILE not updated: %#v", env)
+	}
+	if v := env["LOG_LEVEL"]; v == nil || *v != "info" {
+		t.Fatalf("LOG_LEVEL lost: %#v", env)
+	}
+
+	if _, err = updateComposeFilesAddEnvironment([]string{apps}, vars, []string{"missing"}); err == nil {
+		t.Fatalf("expected error for missing service")
+	}
+}
+
+// mountsFor returns default mounts for services, as a plain --for would.
+func mountsFor(services ...string) []serviceMount {
+	mounts, _ := parseServiceMounts(services, serviceMount{})
+	return mounts
+}
+
+func TestParseServiceMounts(t *testing.T) {
+	mode := types.FileMode(0400)
+	defaults := serviceMount{Target: "/etc/app/key", UID: "1000", Mode: &mode}
+	got, err := parseServiceMounts([]string{"api", "worker:/run/key.pem"}, defaults)
+	if err != nil {
+		t.Fatalf("parseServiceMounts: %v", err)
+	}
+	want := []serviceMount{
+		{Service: "api", Target: "/etc/app/key", UID: "1000", Mode: &mode},
+		{Service: "worker", Target: "/run/key.pem", UID: "1000", Mode: &mode},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mounts mismatch: %#v", got)
+	}
+
+	for _, bad := range []string{":/x", "api:"} {
+		if _, err = parseServiceMounts([]string{bad}, serviceMount{}); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := parseFileMode("0400")
+	if err != nil || mode == nil || *mode != 0400 {
+		t.Fatalf("parseFileMode(0400) = %v, %v", mode, err)
+	}
+	if mode, err = parseFileMode(""); err != nil || mode != nil {
+		t.Fatalf("empty mode should be nil: %v, %v", mode, err)
+	}
+	for _, bad := range []string{"rw", "0999", "1777"} {
+		if _, err = parseFileMode(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestAddComposeSecret_MountOptions(t *testing.T) {
+	p := &types.Project{Services: make(types.Services)}
+	p.Services["api"] = types.ServiceConfig{Image: "x"}
+	p.Services["worker"] = types.ServiceConfig{Image: "x"}
+
+	mode := types.FileMode(0400)
+	mounts, err := parseServiceMounts([]string{"api:/etc/app/key.pem", "worker"}, serviceMount{UID: "1000", GID: "1000", Mode: &mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = addComposeSecret(p, "key.0123abcd", "key", mounts); err != nil {
+		t.Fatalf("add secret: %v", err)
+	}
+
+	api := p.Services["api"].Secrets
+	if len(api) != 1 || api[0].Target != "/etc/app/key.pem" || api[0].UID != "1000" || api[0].GID != "1000" || api[0].Mode == nil || *api[0].Mode != 0400 {
+		t.Fatalf("api mount mismatch: %#v", api)
+	}
+	worker := p.Services["worker"].Secrets
+	if len(worker) != 1 || worker[0].Target != "key" || worker[0].UID != "1000" {
+		t.Fatalf("worker mount mismatch: %#v", worker)
+	}
+
+	// A new version at the same path keeps the ownership and mode.
+	if err = addComposeSecret(p, "key.267da420", "key", mountsFor("api:/etc/app/key.pem")); err != nil {
+		t.Fatalf("add new version: %v", err)
+	}
+	api = p.Services["api"].Secrets
+	if len(api) != 1 || api[0].Source != "key.267da420" || api[0].UID != "1000" || api[0].Mode == nil {
+		t.Fatalf("api mount not swapped in place: %#v", api)
+	}
+}
+
+func TestAddComposeConfig_MountOptions(t *testing.T) {
+	p := &types.Project{Services: make(types.Services)}
+	p.Services["proxy"] = types.ServiceConfig{Image: "x"}
+
+	mode := types.FileMode(0444)
+	mounts, err := parseServiceMounts([]string{"proxy"}, serviceMount{Target: "/etc/nginx/nginx.conf", Mode: &mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = addComposeConfig(p, "nginx.conf.0123abcd", "nginx.conf", mounts); err != nil {
+		t.Fatalf("add config: %v", err)
+	}
+	cfgs := p.Services["proxy"].Configs
+	if len(cfgs) != 1 || cfgs[0].Target != "/etc/nginx/nginx.conf" || cfgs[0].Mode == nil || *cfgs[0].Mode != 0444 {
+		t.Fatalf("proxy mount mismatch: %#v", cfgs)
+	}
+}
+
+func TestUpdateComposeFilesDetachSecret(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	pg := filepath.Join(dir, "compose.postgres.yml")
+	appsYAML := `services:
+  api:
+    image: x
+    secrets: [{source: db.267da420, target: db}]
+  worker:
+    image: x
+    secrets: [{source: db.267da420, target: db}, {source: other, target: other}]
+secrets:
+  db.267da420: {external: true}
+  other: {external: true}
+`
+	pgYAML := `services:
+  postgres:
+    image: postgres
+    secrets: [{source: db.267da420, target: db}]
+`
+	if err := os.WriteFile(apps, []byte(appsYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pg, []byte(pgYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{apps, pg}
+
+	changed, err := updateComposeFilesDetachSecret(files, "db", []string{"worker", "api"})
+	if err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != apps {
+		t.Fatalf("changed files mismatch: %#v", changed)
+	}
+	p, _, err := loadComposeFile(apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Services["api"].Secrets) != 0 {
+		t.Fatalf("api should not mount db anymore: %#v", p.Services["api"].Secrets)
+	}
+	if secs := p.Services["worker"].Secrets; len(secs) != 1 || secs[0].Source != "other" {
+		t.Fatalf("worker should keep other: %#v", secs)
+	}
+	// postgres, in another file, still mounts db so the declaration stays
+	if _, ok := p.Secrets["db.267da420"]; !ok {
+		t.Fatalf("declaration still used by postgres was dropped: %#v", p.Secrets)
+	}
+
+	changed, err = updateComposeFilesDetachSecret(files, "db.267da420", []string{"postgres"})
+	if err != nil {
+		t.Fatalf("detach postgres: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("expected both files to change: %#v", changed)
+	}
+	p, _, _ = loadComposeFile(apps)
+	if _, ok := p.Secrets["db.267da420"]; ok {
+		t.Fatalf("unreferenced declaration should be dropped: %#v", p.Secrets)
+	}
+
+	if _, err = updateComposeFilesDetachSecret(files, "db", []string{"missing"}); err == nil {
+		t.Fatalf("expected error for missing service")
+	}
+}
+
+func TestUpdateComposeFilesDetachConfig(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "compose.apps.yaml")
+	appsYAML := `services:
+  api:
+    image: x
+    configs: [{source: app.conf.0123abcd, target: /app.conf}]
+configs:
+  app.conf.0123abcd: {external: true}
+`
+	if err := os.WriteFile(apps, []byte(appsYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := updateComposeFilesDetachConfig([]string{apps}, "app.conf", []string{"api"})
+	if err != nil || len(changed) != 1 {
+		t.Fatalf("detach = %#v, %v", changed, err)
+	}
+	p, _, _ := loadComposeFile(apps)
+	if len(p.Services["api"].Configs) != 0 || len(p.Configs) != 0 {
+		t.Fatalf("config not detached: %#v %#v", p.Services["api"].Configs, p.Configs)
+	}
+}
+
+func TestRegistryRetags(t *testing.T) {
+	build := &types.BuildConfig{Context: "."}
+	source := &types.Project{Services: types.Services{
+		"api":    {Name: "api", Image: "api:latest", Build: build},
+		"worker": {Name: "worker", Image: "acme/worker", Build: build},
+		"custom": {Name: "custom", Image: "custom", Build: build},
+		"db":     {Name: "db", Image: "postgres:17"},
+	}}
+	apps := &types.Project{Services: types.Services{
+		"api":    {Name: "api", Image: "registry:5000/api:${MINIPAAS_DEPLOY_VERSION}"},
+		"worker": {Name: "worker", Image: "acme/worker:${MINIPAAS_DEPLOY_VERSION}"},
+		"custom": {Name: "custom", Image: "custom-image:stable"},
+		"db":     {Name: "db"},
+	}}
+
+	got := registryRetags(apps, source, "ghcr.io/acme")
+	want := []imageRetag{{Service: "api", From: "registry:5000/api:${MINIPAAS_DEPLOY_VERSION}", To: "ghcr.io/acme/api:${MINIPAAS_DEPLOY_VERSION}"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("registryRetags = %#v, want %#v", got, want)
+	}
+	if got := registryRetags(apps, source, defaultRegistry); len(got) != 0 {
+		t.Fatalf("no retag expected for the current registry, got %#v", got)
+	}
+
+	applyRegistryRetags(apps, want, "1.2.0")
+	if img := apps.Services["api"].Image; img != "ghcr.io/acme/api:1.2.0" {
+		t.Fatalf("retagged image = %q", img)
+	}
+}
@@ -2,12 +2,19 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/goccy/go-yaml"
 )
 
+// defaultRegistry is the registry service of the minipaas stack, as seen
+// from the cluster nodes.
+const defaultRegistry = "registry:5000"
+
 type ProjectConfig struct {
 	Files []string `yaml:"files"`
 }
@@ -19,13 +26,39 @@ type ApiConfig struct {
 }
 
 type DeployConfig struct {
-	Version string `yaml:"version"`
+	Version string      `yaml:"version"`
+	Hooks   HooksConfig `yaml:"hooks,omitempty"`
+	Waves   [][]string  `yaml:"waves,omitempty"`
+}
+
+// HooksConfig lists the steps `deploy rollout` runs around the deployment.
+type HooksConfig struct {
+	PreRollout  []HookStep `yaml:"pre_rollout,omitempty"`
+	PostRollout []HookStep `yaml:"post_rollout,omitempty"`
+	OnFailure   []HookStep `yaml:"on_failure,omitempty"`
+}
+
+// HookStep is either a job service of the stack or a local shell command.
+type HookStep struct {
+	Job string `yaml:"job,omitempty"`
+	Run string `yaml:"run,omitempty"`
 }
 
 type Config struct {
-	Project ProjectConfig `yaml:"project"`
-	Api     ApiConfig     `yaml:"api"`
-	Deploy  DeployConfig  `yaml:"deploy"`
+	Stack    string              `yaml:"stack,omitempty"`
+	Registry string              `yaml:"registry,omitempty"`
+	Project  ProjectConfig       `yaml:"project"`
+	Api      ApiConfig           `yaml:"api"`
+	Deploy   DeployConfig        `yaml:"deploy"`
+	Env      map[string]EnvValue `yaml:"env,omitempty"`
+}
+
+// RegistryName returns the registry path built images are pushed to.
+func (c Config) RegistryName() string {
+	if c.Registry == "" {
+		return defaultRegistry
+	}
+	return strings.TrimSuffix(c.Registry, "/")
 }
 
 func loadConfig(env string) (Config, string, error) {
@@ -39,6 +72,15 @@ func loadConfig(env string) (Config, string, error) {
 	if err = yaml.Unmarshal(data, &cfg); err != nil {
 		return Config{}, fn, err
 	}
+	if err = validateStackName(cfg.Stack); err != nil {
+		return Config{}, fn, err
+	}
+	if err = validateHooks(cfg.Deploy.Hooks); err != nil {
+		return Config{}, fn, err
+	}
+	if err = validateWaves(cfg.Deploy.Waves); err != nil {
+		return Config{}, fn, err
+	}
 
 	return cfg, fn, nil
 }
@@ -50,16 +92,32 @@ func saveConfig(env string, cfg Config) (string, error) {
 		return fn, err
 	}
 
-	return fn, os.WriteFile(fn, data, 0644)
+	return fn, writeFile(fn, data, 0644)
 }
 
 func setApiEnvVars(env string, cfg Config, verbose bool) {
+	// The env block is exported too, so compose interpolation, build args
+	// and `docker stack deploy` see it.
+	vars, err := resolveEnvValues(env, cfg.Env)
+	checkErrorPanic(err, "❌ Failed to resolve env in minipaas.yaml")
+
 	// Local mode: use default Docker socket, no TLS envs
 	if verbose {
 		fmt.Printf("🔹 Environment:\n"+
 			"   MINIPAAS_DEPLOY_VERSION=%s\n", cfg.Deploy.Version)
+		// Values may be sensitive, only their names and sources are shown.
+		for _, name := range slices.Sorted(maps.Keys(vars)) {
+			if source := cfg.Env[name].source(); source != "" {
+				fmt.Printf("   %s (%s)\n", name, source)
+			} else {
+				fmt.Printf("   %s (from minipaas.yaml)\n", name)
+			}
+		}
 	}
 	os.Setenv("MINIPAAS_DEPLOY_VERSION", cfg.Deploy.Version)
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
 
 	if cfg.Api.Local {
 		return
@@ -70,3 +70,12 @@ func TestSetApiEnvVars_LocalAndTLS(t *testing.T) {
 		t.Fatalf("cert path mismatch: %q", os.Getenv("DOCKER_CERT_PATH"))
 	}
 }
+
+func TestConfigRegistryName(t *testing.T) {
+	if got := (Config{}).RegistryName(); got != "registry:5000" {
+		t.Fatalf("default registry = %q", got)
+	}
+	if got := (Config{Registry: "ghcr.io/acme/"}).RegistryName(); got != "ghcr.io/acme" {
+		t.Fatalf("registry = %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// EnvValue is a value of the env block of minipaas.yaml: a plain string, or
+// a reference to a file of the env directory or to a variable of the process
+// environment, with an optional default.
+//
+//	env:
+//	  APP_ENV: production
+//	  SENTRY_DSN: {env: SENTRY_DSN}
+//	  LOG_LEVEL: {env: LOG_LEVEL, value: info}
+//	  BUILD_INFO: {file: build-info.txt}
+type EnvValue struct {
+	Value string `yaml:"value,omitempty"`
+	File  string `yaml:"file,omitempty"`
+	Env   string `yaml:"env,omitempty"`
+}
+
+func (v *EnvValue) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*v = EnvValue{Value: s}
+		return nil
+	}
+	type plain EnvValue
+	var p plain
+	if err := unmarshal(&p); err != nil {
+		return err
+	}
+	*v = EnvValue(p)
+	return nil
+}
+
+func (v EnvValue) MarshalYAML() (any, error) {
+	if v.File == "" && v.Env == "" {
+		return v.Value, nil
+	}
+	type plain EnvValue
+	return plain(v), nil
+}
+
+// source describes where a resolved value comes from, for verbose output.
+func (v EnvValue) source() string {
+	switch {
+	case v.File != "":
+		return "from file " + v.File
+	case v.Env != "":
+		return "from $" + v.Env
+	}
+	return ""
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnvVars are set by the CLI itself from the rest of minipaas.yaml.
+var reservedEnvVars = []string{"MINIPAAS_DEPLOY_VERSION", "DOCKER_HOST", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY", rolloutErrorEnv}
+
+// resolveEnvValues reads the references of the env block. Files are relative
+// to the env directory and lose their trailing newline; a missing variable
+// falls back to the value given with it, if any.
+func resolveEnvValues(env string, values map[string]EnvValue) (map[string]string, error) {
+	resolved := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		v := values[name]
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("env %s: invalid variable name", name)
+		}
+		if slices.Contains(reservedEnvVars, name) {
+			return nil, fmt.Errorf("env %s: set by minipaas from the rest of minipaas.yaml", name)
+		}
+
+		switch {
+		case v.File != "" && v.Env != "":
+			return nil, fmt.Errorf("env %s: set either file or env, not both", name)
+		case v.File != "":
+			fn := v.File
+			if !filepath.IsAbs(fn) {
+				fn = filepath.Join(env, fn)
+			}
+			data, err := os.ReadFile(fn)
+			if err != nil {
+				return nil, fmt.Errorf("env %s: %w", name, err)
+			}
+			resolved[name] = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		case v.Env != "":
+			value, ok := os.LookupEnv(v.Env)
+			if !ok {
+				if v.Value == "" {
+					return nil, fmt.Errorf("env %s: variable %s is not set", name, v.Env)
+				}
+				value = v.Value
+			}
+			resolved[name] = value
+		default:
+			resolved[name] = v.Value
+		}
+	}
+	return resolved, nil
+}
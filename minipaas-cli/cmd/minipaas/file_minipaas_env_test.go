@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigEnv_YAMLRoundtrip(t *testing.T) {
+	dir := t.TempDir()
+	content := `project:
+  files: []
+api:
+  local: true
+deploy:
+  version: 1.0.0
+env:
+  APP_ENV: production
+  PORT: 8080
+  SENTRY_DSN:
+    env: SENTRY_DSN
+  BUILD_INFO:
+    file: build-info.txt
+`
+	if err := os.WriteFile(filepath.Join(dir, "minipaas.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := loadConfig(dir)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	want := map[string]EnvValue{
+		"APP_ENV":    {Value: "production"},
+		"PORT":       {Value: "8080"},
+		"SENTRY_DSN": {Env: "SENTRY_DSN"},
+		"BUILD_INFO": {File: "build-info.txt"},
+	}
+	if !reflect.DeepEqual(cfg.Env, want) {
+		t.Fatalf("env = %#v, want %#v", cfg.Env, want)
+	}
+
+	if _, err = saveConfig(dir, cfg); err != nil {
+		t.Fatalf("saveConfig: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "minipaas.yaml"))
+	if !strings.Contains(string(data), "APP_ENV: production") || !strings.Contains(string(data), "env: SENTRY_DSN") {
+		t.Fatalf("unexpected saved env:\n%s", data)
+	}
+	again, _, err := loadConfig(dir)
+	if err != nil || !reflect.DeepEqual(again.Env, want) {
+		t.Fatalf("roundtrip env = %#v (%v)", again.Env, err)
+	}
+}
+
+func TestResolveEnvValues(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "info.txt"), []byte("built by ci\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MP_TEST_DSN", "https://sentry")
+
+	got, err := resolveEnvValues(dir, map[string]EnvValue{
+		"APP_ENV":    {Value: "production"},
+		"SENTRY_DSN": {Env: "MP_TEST_DSN"},
+		"LOG_LEVEL":  {Env: "MP_TEST_UNSET", Value: "info"},
+		"BUILD_INFO": {File: "info.txt"},
+	})
+	if err != nil {
+		t.Fatalf("resolveEnvValues: %v", err)
+	}
+	want := map[string]string{
+		"APP_ENV":    "production",
+		"SENTRY_DSN": "https://sentry",
+		"LOG_LEVEL":  "info",
+		"BUILD_INFO": "built by ci",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolved = %v, want %v", got, want)
+	}
+
+	for name, values := range map[string]map[string]EnvValue{
+		"unset variable": {"DSN": {Env: "MP_TEST_UNSET"}},
+		"missing file":   {"INFO": {File: "missing.txt"}},
+		"both refs":      {"INFO": {File: "info.txt", Env: "MP_TEST_DSN"}},
+		"invalid name":   {"BAD-NAME": {Value: "x"}},
+		"reserved name":  {"MINIPAAS_DEPLOY_VERSION": {Value: "x"}},
+	} {
+		if _, err := resolveEnvValues(dir, values); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSetApiEnvVars_EnvBlockInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MINIPAAS_DEPLOY_VERSION", "")
+	t.Setenv("APP_ENV", "")
+	t.Setenv("NPM_TOKEN", "")
+
+	cfg := Config{
+		Api:    ApiConfig{Local: true},
+		Deploy: DeployConfig{Version: "1.2.3"},
+		Env: map[string]EnvValue{
+			"APP_ENV":   {Value: "staging"},
+			"NPM_TOKEN": {Value: "token"},
+		},
+	}
+	setApiEnvVars(dir, cfg, false)
+
+	fn := filepath.Join(dir, "compose.yaml")
+	content := `services:
+  api:
+    image: api:${MINIPAAS_DEPLOY_VERSION}
+    environment:
+      APP_ENV: ${APP_ENV}
+    build:
+      context: .
+      args:
+        - NPM_TOKEN
+        - UNDECLARED
+`
+	if err := os.WriteFile(fn, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := composeLoadDeployProject([]string{fn}, defaultStack)
+	if err != nil {
+		t.Fatalf("composeLoadDeployProject: %v", err)
+	}
+	api := project.Services["api"]
+	if api.Image != "api:1.2.3" || *api.Environment["APP_ENV"] != "staging" {
+		t.Fatalf("not interpolated: image=%q env=%v", api.Image, api.Environment)
+	}
+	// Build args declared without a value take it from the env block.
+	if arg := api.Build.Args["NPM_TOKEN"]; arg == nil || *arg != "token" {
+		t.Fatalf("NPM_TOKEN build arg = %v", arg)
+	}
+	if _, ok := api.Build.Args["UNDECLARED"]; ok {
+		t.Fatalf("unset build args should be dropped")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	historyFile  = "history.yaml"
+	historyLimit = 50
+)
+
+// DeployHistory is the record of the rollouts of an env, oldest first. It is
+// committable, so the team can see what ran where and roll back to it.
+type DeployHistory struct {
+	Entries []DeployEntry `yaml:"entries"`
+}
+
+// DeployEntry is one rollout. Images holds the exact image reference (with
+// digest, as pinned by Swarm) each stack service ran once it was deployed.
+type DeployEntry struct {
+	Number     int               `yaml:"number"`
+	Version    string            `yaml:"version"`
+	Time       time.Time         `yaml:"time"`
+	Operator   string            `yaml:"operator,omitempty"`
+	GitSHA     string            `yaml:"git_sha,omitempty"`
+	RollbackTo int               `yaml:"rollback_to,omitempty"`
+	Images     map[string]string `yaml:"images"`
+}
+
+// loadDeployHistory reads env/history.yaml. A missing file yields an empty
+// history so the first rollout can create it.
+func loadDeployHistory(env string) (DeployHistory, string, error) {
+	fn := filepath.Join(env, historyFile)
+	data, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return DeployHistory{}, fn, nil
+	}
+	if err != nil {
+		return DeployHistory{}, fn, err
+	}
+
+	var history DeployHistory
+	if err = yaml.Unmarshal(data, &history); err != nil {
+		return DeployHistory{}, fn, err
+	}
+	return history, fn, nil
+}
+
+func saveDeployHistory(env string, history DeployHistory) (string, error) {
+	fn := filepath.Join(env, historyFile)
+	data, err := yaml.Marshal(history)
+	if err != nil {
+		return fn, err
+	}
+	return fn, writeFile(fn, data, 0644)
+}
+
+// add numbers entry after the last one and appends it, dropping the oldest
+// entries beyond historyLimit.
+func (h *DeployHistory) add(entry DeployEntry) DeployEntry {
+	entry.Number = 1
+	if n := len(h.Entries); n > 0 {
+		entry.Number = h.Entries[n-1].Number + 1
+	}
+	h.Entries = append(h.Entries, entry)
+	if extra := len(h.Entries) - historyLimit; extra > 0 {
+		h.Entries = h.Entries[extra:]
+	}
+	return entry
+}
+
+// find returns the entry with the given number.
+func (h DeployHistory) find(number int) (DeployEntry, bool) {
+	for _, e := range h.Entries {
+		if e.Number == number {
+			return e, true
+		}
+	}
+	return DeployEntry{}, false
+}
+
+// rollbackTarget returns the entry to roll back to: the one numbered to, or
+// when to is 0 the entry before the one currently deployed. After a rollback
+// the current deployment is the restored entry, so repeated rollbacks keep
+// walking back.
+func (h DeployHistory) rollbackTarget(to int) (DeployEntry, error) {
+	if to != 0 {
+		entry, ok := h.find(to)
+		if !ok {
+			return DeployEntry{}, fmt.Errorf("no entry #%d in history", to)
+		}
+		return entry, nil
+	}
+	if len(h.Entries) == 0 {
+		return DeployEntry{}, errors.New("history is empty")
+	}
+
+	current := h.Entries[len(h.Entries)-1].Number
+	if restored := h.Entries[len(h.Entries)-1].RollbackTo; restored != 0 {
+		current = restored
+	}
+	for i := len(h.Entries) - 1; i >= 0; i-- {
+		if h.Entries[i].Number < current {
+			return h.Entries[i], nil
+		}
+	}
+	return DeployEntry{}, fmt.Errorf("no entry before #%d in history", current)
+}
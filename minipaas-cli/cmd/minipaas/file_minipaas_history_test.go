@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func historyWith(entries ...DeployEntry) DeployHistory {
+	var h DeployHistory
+	for _, e := range entries {
+		h.add(e)
+	}
+	return h
+}
+
+func TestDeployHistory_SaveAndLoad(t *testing.T) {
+	env := t.TempDir()
+
+	history, _, err := loadDeployHistory(env)
+	if err != nil || len(history.Entries) != 0 {
+		t.Fatalf("expected empty history for missing file, got %+v, %v", history, err)
+	}
+
+	deployed := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	entry := history.add(DeployEntry{
+		Version: "1.2.0",
+		Time:    deployed,
+		GitSHA:  "abc123",
+		Images:  map[string]string{"api": "registry:5000/api:1.2.0@sha256:abc"},
+	})
+	if entry.Number != 1 {
+		t.Fatalf("expected first entry to be #1, got %d", entry.Number)
+	}
+	if _, err := saveDeployHistory(env, history); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _, err := loadDeployHistory(env)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	got := loaded.Entries[0]
+	if got.Version != "1.2.0" || !got.Time.Equal(deployed) || got.Images["api"] != "registry:5000/api:1.2.0@sha256:abc" {
+		t.Fatalf("unexpected entry %+v", got)
+	}
+}
+
+func TestDeployHistory_AddKeepsLimit(t *testing.T) {
+	var h DeployHistory
+	for i := 0; i < historyLimit+5; i++ {
+		h.add(DeployEntry{Version: "v"})
+	}
+	if len(h.Entries) != historyLimit || h.Entries[0].Number != 6 || h.Entries[historyLimit-1].Number != historyLimit+5 {
+		t.Fatalf("unexpected entries: first #%d, last #%d, len %d", h.Entries[0].Number, h.Entries[len(h.Entries)-1].Number, len(h.Entries))
+	}
+}
+
+func TestDeployHistory_RollbackTarget(t *testing.T) {
+	h := historyWith(DeployEntry{Version: "1"}, DeployEntry{Version: "2"}, DeployEntry{Version: "3"})
+
+	target, err := h.rollbackTarget(0)
+	if err != nil || target.Number != 2 {
+		t.Fatalf("expected #2, got %+v, %v", target, err)
+	}
+
+	// After rolling back to #2, the next rollback goes to #1.
+	h.add(DeployEntry{Version: "2", RollbackTo: 2})
+	target, err = h.rollbackTarget(0)
+	if err != nil || target.Number != 1 {
+		t.Fatalf("expected #1, got %+v, %v", target, err)
+	}
+
+	if target, err = h.rollbackTarget(3); err != nil || target.Version != "3" {
+		t.Fatalf("expected explicit #3, got %+v, %v", target, err)
+	}
+	if _, err = h.rollbackTarget(9); err == nil {
+		t.Fatalf("expected error for unknown entry")
+	}
+
+	h.add(DeployEntry{Version: "1", RollbackTo: 1})
+	if _, err = h.rollbackTarget(0); err == nil {
+		t.Fatalf("expected error when nothing precedes the current deployment")
+	}
+}
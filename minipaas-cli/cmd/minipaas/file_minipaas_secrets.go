@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	secretsFile         = "secrets.enc.yaml"
+	secretsStoreVersion = 1
+	secretsStoreCipher  = "aes-256-gcm"
+	secretsKeySize      = 32
+)
+
+// SecretsStore is the committable, encrypted secrets file of an env. Names and
+// target services stay readable so changes can be reviewed; only values are
+// sealed, each bound to its name as additional authenticated data.
+type SecretsStore struct {
+	Version int                     `yaml:"version"`
+	Cipher  string                  `yaml:"cipher"`
+	Secrets map[string]SealedSecret `yaml:"secrets"`
+}
+
+type SealedSecret struct {
+	For   []string `yaml:"for,omitempty"`
+	Value string   `yaml:"value"`
+}
+
+// PlainSecret is the decrypted form of a SealedSecret, used by unseal.
+type PlainSecret struct {
+	For   []string `yaml:"for,omitempty"`
+	Value string   `yaml:"value"`
+}
+
+func newSecretsStore() SecretsStore {
+	return SecretsStore{
+		Version: secretsStoreVersion,
+		Cipher:  secretsStoreCipher,
+		Secrets: map[string]SealedSecret{},
+	}
+}
+
+// loadSecretsStore reads env/secrets.enc.yaml. A missing file yields an empty
+// store so the first seal can create it.
+func loadSecretsStore(env string) (SecretsStore, string, error) {
+	fn := filepath.Join(env, secretsFile)
+	data, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return newSecretsStore(), fn, nil
+	}
+	if err != nil {
+		return SecretsStore{}, fn, err
+	}
+
+	store := newSecretsStore()
+	if err = yaml.Unmarshal(data, &store); err != nil {
+		return SecretsStore{}, fn, err
+	}
+	if store.Cipher != secretsStoreCipher {
+		return SecretsStore{}, fn, fmt.Errorf("unsupported cipher %q", store.Cipher)
+	}
+	if store.Secrets == nil {
+		store.Secrets = map[string]SealedSecret{}
+	}
+	return store, fn, nil
+}
+
+func saveSecretsStore(env string, store SecretsStore) (string, error) {
+	fn := filepath.Join(env, secretsFile)
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return fn, err
+	}
+	return fn, writeFile(fn, data, 0644)
+}
+
+// loadSecretsKey reads a base64 encoded 256-bit key.
+func loadSecretsKey(fn string) ([]byte, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s is not valid base64: %w", fn, err)
+	}
+	if len(key) != secretsKeySize {
+		return nil, fmt.Errorf("key file %s must hold %d bytes, got %d", fn, secretsKeySize, len(key))
+	}
+	return key, nil
+}
+
+// generateSecretsKey writes a new random key readable only by the owner.
+func generateSecretsKey(fn string) ([]byte, error) {
+	key := make([]byte, secretsKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		// Never print key material; the store diff is enough to review.
+		fmt.Fprintf(dryRunOutput, "🔹 [dry-run] would generate key file %s\n", fn)
+		return key, nil
+	}
+	if err := mkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := writeFile(fn, []byte(encoded), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func secretsAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSecretValue encrypts plaintext for name, returning base64(nonce|ciphertext).
+func sealSecretValue(key []byte, name string, plaintext []byte) (string, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// unsealSecretValue decrypts a value produced by sealSecretValue for name.
+func unsealSecretValue(key []byte, name string, value string) ([]byte, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", name, err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("secret %s: sealed value too short", name)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: wrong key or tampered value", name)
+	}
+	return plaintext, nil
+}
+
+// sealSecretEntry returns the sealed form of plaintext, reusing the current
+// ciphertext when the value did not change so unchanged entries produce no
+// diff in git.
+func sealSecretEntry(key []byte, name string, plaintext []byte, current SealedSecret, exists bool) (string, error) {
+	if exists {
+		if old, err := unsealSecretValue(key, name, current.Value); err == nil && bytes.Equal(old, plaintext) {
+			return current.Value, nil
+		}
+	}
+	return sealSecretValue(key, name, plaintext)
+}
+
+// unsealSecretsStore decrypts every entry of the store.
+func unsealSecretsStore(key []byte, store SecretsStore) (map[string]PlainSecret, error) {
+	plain := map[string]PlainSecret{}
+	for name, sealed := range store.Secrets {
+		value, err := unsealSecretValue(key, name, sealed.Value)
+		if err != nil {
+			return nil, err
+		}
+		plain[name] = PlainSecret{For: sealed.For, Value: string(value)}
+	}
+	return plain, nil
+}
+
+// sealSecretsStore replaces the store entries with the sealed plain entries.
+func sealSecretsStore(key []byte, store SecretsStore, plain map[string]PlainSecret) (SecretsStore, error) {
+	sealed := newSecretsStore()
+	for name, entry := range plain {
+		if name == "" || objectBaseName(name) != name {
+			return SecretsStore{}, fmt.Errorf("invalid secret name %q: use the base name without hash suffix", name)
+		}
+		current, exists := store.Secrets[name]
+		value, err := sealSecretEntry(key, name, []byte(entry.Value), current, exists)
+		if err != nil {
+			return SecretsStore{}, err
+		}
+		sealed.Secrets[name] = SealedSecret{For: entry.For, Value: value}
+	}
+	return sealed, nil
+}
+
+// secretsKey loads the key from fn. When allowCreate is set and the file does
+// not exist yet, a new key is generated and created reports it.
+func secretsKey(fn string, allowCreate bool) (key []byte, created bool, err error) {
+	key, err = loadSecretsKey(fn)
+	if err == nil || !allowCreate || !errors.Is(err, os.ErrNotExist) {
+		return key, false, err
+	}
+	key, err = generateSecretsKey(fn)
+	return key, err == nil, err
+}
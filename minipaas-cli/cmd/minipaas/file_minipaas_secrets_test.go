@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealUnsealSecretValue(t *testing.T) {
+	key, err := generateSecretsKey(filepath.Join(t.TempDir(), "keys", "dev.key"))
+	if err != nil {
+		t.Fatalf("generateSecretsKey: %v", err)
+	}
+
+	sealed, err := sealSecretValue(key, "db", []byte("postgres\n"))
+	if err != nil {
+		t.Fatalf("sealSecretValue: %v", err)
+	}
+	plain, err := unsealSecretValue(key, "db", sealed)
+	if err != nil || string(plain) != "postgres\n" {
+		t.Fatalf("roundtrip mismatch: %q %v", plain, err)
+	}
+
+	// values are bound to their name
+	if _, err = unsealSecretValue(key, "other", sealed); err == nil {
+		t.Fatalf("expected error when unsealing under another name")
+	}
+
+	other, _ := generateSecretsKey(filepath.Join(t.TempDir(), "other.key"))
+	if _, err = unsealSecretValue(other, "db", sealed); err == nil {
+		t.Fatalf("expected error with wrong key")
+	}
+}
+
+func TestSecretsKey_CreateOnlyWhenAllowed(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "dev.key")
+
+	if _, _, err := secretsKey(fn, false); err == nil {
+		t.Fatalf("expected error for missing key when creation is not allowed")
+	}
+
+	key, created, err := secretsKey(fn, true)
+	if err != nil || !created || len(key) != secretsKeySize {
+		t.Fatalf("key not generated: created=%v len=%d err=%v", created, len(key), err)
+	}
+	info, err := os.Stat(fn)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file permissions mismatch: %v %v", info, err)
+	}
+
+	again, created, err := secretsKey(fn, true)
+	if err != nil || created || string(again) != string(key) {
+		t.Fatalf("existing key should be loaded, not regenerated")
+	}
+}
+
+func TestSecretsStore_SealRoundTripKeepsUnchangedCiphertext(t *testing.T) {
+	dir := t.TempDir()
+	key, err := generateSecretsKey(filepath.Join(t.TempDir(), "dev.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, _, err := loadSecretsStore(dir)
+	if err != nil || len(store.Secrets) != 0 {
+		t.Fatalf("missing store should load empty: %#v %v", store, err)
+	}
+
+	plain := map[string]PlainSecret{
+		"db":      {For: []string{"api", "worker"}, Value: "postgres"},
+		"api_key": {Value: "abc"},
+	}
+	store, err = sealSecretsStore(key, store, plain)
+	if err != nil {
+		t.Fatalf("sealSecretsStore: %v", err)
+	}
+	if _, err = saveSecretsStore(dir, store); err != nil {
+		t.Fatalf("saveSecretsStore: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, secretsFile))
+	if err != nil {
+		t.Fatalf("store not written: %v", err)
+	}
+	if strings.Contains(string(data), "postgres") || strings.Contains(string(data), "abc") {
+		t.Fatalf("plaintext leaked into store:\n%s", data)
+	}
+
+	loaded, _, err := loadSecretsStore(dir)
+	if err != nil {
+		t.Fatalf("loadSecretsStore: %v", err)
+	}
+	got, err := unsealSecretsStore(key, loaded)
+	if err != nil || got["db"].Value != "postgres" || len(got["db"].For) != 2 {
+		t.Fatalf("unseal mismatch: %#v %v", got, err)
+	}
+
+	plain["api_key"] = PlainSecret{Value: "rotated"}
+	resealed, err := sealSecretsStore(key, loaded, plain)
+	if err != nil {
+		t.Fatalf("reseal: %v", err)
+	}
+	if resealed.Secrets["db"].Value != loaded.Secrets["db"].Value {
+		t.Fatalf("unchanged entry should keep its ciphertext")
+	}
+	if resealed.Secrets["api_key"].Value == loaded.Secrets["api_key"].Value {
+		t.Fatalf("changed entry should be re-sealed")
+	}
+}
+
+func TestSealSecretsStore_RejectsHashedNames(t *testing.T) {
+	key := make([]byte, secretsKeySize)
+	_, err := sealSecretsStore(key, newSecretsStore(), map[string]PlainSecret{"db.267da420": {Value: "x"}})
+	if err == nil {
+		t.Fatalf("expected error for hashed name")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+)
+
+const valuesFile = "values.yaml"
+
+// TemplateData is what `config create --template` exposes to a template:
+// {{ .Deploy.Version }}, {{ .Api.Host }}, {{ .Vars.HOME }}, {{ .Values.x }}.
+type TemplateData struct {
+	Env    string
+	Deploy DeployConfig
+	Api    ApiConfig
+	Vars   map[string]string
+	Values map[string]any
+}
+
+// loadTemplateValues reads the per-env values file. When fn is empty the
+// default env/values.yaml is used, and a missing default yields no values.
+func loadTemplateValues(env string, fn string) (map[string]any, error) {
+	optional := fn == ""
+	if optional {
+		fn = filepath.Join(env, valuesFile)
+	}
+	data, err := os.ReadFile(fn)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// environVars returns the process environment as a map.
+func environVars() map[string]string {
+	vars := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+func newTemplateData(env string, cfg Config, values map[string]any) TemplateData {
+	return TemplateData{
+		Env:    filepath.Base(env),
+		Deploy: cfg.Deploy,
+		Api:    cfg.Api,
+		Vars:   environVars(),
+		Values: values,
+	}
+}
+
+// renderTemplate executes content as a text/template. Unknown keys are an
+// error so a typo never ends up in a deployed config.
+func renderTemplate(name string, content []byte, data TemplateData) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err = tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTemplateValues(t *testing.T) {
+	dir := t.TempDir()
+	values, err := loadTemplateValues(dir, "")
+	if err != nil || len(values) != 0 {
+		t.Fatalf("missing default values file should be empty: %v %#v", err, values)
+	}
+	if _, err = loadTemplateValues(dir, filepath.Join(dir, "other.yaml")); err == nil {
+		t.Fatalf("expected error for missing explicit values file")
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, valuesFile), []byte("workers: 4\nupstream:\n  host: api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values, err = loadTemplateValues(dir, "")
+	if err != nil {
+		t.Fatalf("loadTemplateValues: %v", err)
+	}
+	if values["workers"] != uint64(4) {
+		t.Fatalf("workers mismatch: %#v", values["workers"])
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	t.Setenv("MINIPAAS_TEST_REGION", "eu")
+	cfg := Config{Api: ApiConfig{Host: "tcp://prod:2376"}, Deploy: DeployConfig{Version: "1.2.3"}}
+	data := newTemplateData("/tmp/envs/prod", cfg, map[string]any{"workers": 4})
+
+	tmpl := "{{ .Env }} {{ .Deploy.Version }} {{ .Api.Host }} {{ .Vars.MINIPAAS_TEST_REGION }} {{ .Values.workers }}"
+	out, err := renderTemplate("nginx.conf", []byte(tmpl), data)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	if string(out) != "prod 1.2.3 tcp://prod:2376 eu 4" {
+		t.Fatalf("rendered mismatch: %q", out)
+	}
+
+	if _, err = renderTemplate("nginx.conf", []byte("{{ .Values.missing }}"), data); err == nil {
+		t.Fatalf("expected error for missing key")
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/alexflint/go-arg"
 	"log"
+	"os"
 )
 
 /***********
@@ -18,8 +20,14 @@ var args struct {
 	DeploySubcommand *DeploySubcommand `arg:"subcommand:deploy"`
 
 	Shell *ShellArgs `arg:"subcommand:shell"`
+
+	DryRun bool `arg:"--dry-run" help:"Print the commands and file changes instead of applying them" default:"false"`
 }
 
+// exitCode is the status the CLI exits with once the command returned, for
+// commands that report the outcome of what they ran, like `deploy job run`.
+var exitCode int
+
 /***********
 CONFIG
 ************/
@@ -27,6 +35,11 @@ CONFIG
 func main() {
 	arg.MustParse(&args)
 
+	dryRun = args.DryRun
+	if dryRun {
+		fmt.Println("🔹 Dry run: commands and file changes are printed, nothing is applied")
+	}
+
 	switch {
 	case args.CertsSubcommand != nil:
 		args.CertsSubcommand.Run()
@@ -48,4 +61,5 @@ func main() {
 	default:
 		log.Fatal(errors.New("command not supported"))
 	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sombrahq/minipaas/minipaas-cli/internal/dockerapi"
+)
+
+const (
+	// defaultStack is the stack used when minipaas.yaml sets none. It also
+	// runs the Caddy and registry services shared by the other stacks.
+	defaultStack = "minipaas"
+	// sharedNetwork is the compose key of the overlay network Caddy uses to
+	// reach the stack services.
+	sharedNetwork = "minipaas_network"
+	caddyService  = "caddy"
+)
+
+// StackName returns the Swarm stack the environment deploys to.
+func (c Config) StackName() string {
+	if c.Stack == "" {
+		return defaultStack
+	}
+	return c.Stack
+}
+
+// stackService returns the Swarm name of a compose service in stack.
+func stackService(stack, service string) string {
+	return stack + "_" + service
+}
+
+// stackFilter selects the services deployed by `docker stack deploy`.
+func stackFilter(stack string) dockerapi.Filters {
+	return dockerapi.Filters{"label": {"com.docker.stack.namespace=" + stack}}
+}
+
+// validateStackName rejects names Swarm would refuse or that would make the
+// service names of two stacks ambiguous.
+func validateStackName(stack string) error {
+	if stack == "" {
+		return nil
+	}
+	for _, r := range stack {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("invalid stack name %q: use lowercase letters, digits and '-'", stack)
+		}
+	}
+	if strings.HasPrefix(stack, "-") {
+		return fmt.Errorf("invalid stack name %q: must start with a letter or digit", stack)
+	}
+	return nil
+}
+
+// caddyContainerID returns a running container of the Caddy service,
+// looking in the stack first and then in the shared default stack.
+func caddyContainerID(stack string) (string, error) {
+	names := []string{stackService(stack, caddyService)}
+	if stack != defaultStack {
+		names = append(names, stackService(defaultStack, caddyService))
+	}
+	var lastErr error
+	for _, name := range names {
+		id, err := getContainerID(name)
+		if err == nil {
+			return id, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// unsetDerivedNames drops the names compose-go derives from the project name
+// for networks, volumes, secrets and configs, so that saved compose files
+// follow the stack they are deployed to instead of the loader's name.
+func unsetDerivedNames(project *types.Project) {
+	derived := func(key, name string) bool {
+		return project.Name != "" && name == project.Name+"_"+key
+	}
+	for key, n := range project.Networks {
+		if !bool(n.External) && derived(key, n.Name) {
+			n.Name = ""
+			project.Networks[key] = n
+		}
+	}
+	for key, v := range project.Volumes {
+		if !bool(v.External) && derived(key, v.Name) {
+			v.Name = ""
+			project.Volumes[key] = v
+		}
+	}
+	for key, s := range project.Secrets {
+		if !bool(s.External) && derived(key, s.Name) {
+			s.Name = ""
+			project.Secrets[key] = s
+		}
+	}
+	for key, c := range project.Configs {
+		if !bool(c.External) && derived(key, c.Name) {
+			c.Name = ""
+			project.Configs[key] = c
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigStackName(t *testing.T) {
+	if got := (Config{}).StackName(); got != "minipaas" {
+		t.Fatalf("default stack = %q", got)
+	}
+	if got := (Config{Stack: "shop"}).StackName(); got != "shop" {
+		t.Fatalf("stack = %q", got)
+	}
+	if got := canaryServiceName("shop", "api"); got != "shop_api_canary" {
+		t.Fatalf("canaryServiceName = %q", got)
+	}
+}
+
+func TestLoadConfig_InvalidStack(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "minipaas.yaml"), []byte("stack: My_App\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadConfig(dir); err == nil || !strings.Contains(err.Error(), "invalid stack name") {
+		t.Fatalf("expected invalid stack error, got %v", err)
+	}
+}
+
+func TestSaveComposeFile_DropsDerivedNames(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "compose.yml")
+	content := `services:
+  db:
+    image: postgres
+    volumes:
+      - data:/var/lib/postgresql/data
+    secrets: [local, shared]
+volumes:
+  data:
+  pinned:
+    name: pinned_volume
+secrets:
+  local:
+    file: ./local.txt
+  shared:
+    external: true
+`
+	if err := os.WriteFile(fn, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := loadComposeFile(fn)
+	if err != nil {
+		t.Fatalf("loadComposeFile: %v", err)
+	}
+	if _, err = saveComposeFile(fn, project); err != nil {
+		t.Fatalf("saveComposeFile: %v", err)
+	}
+	data, _ := os.ReadFile(fn)
+	out := string(data)
+	if strings.Contains(out, "minipaas_data") || strings.Contains(out, "minipaas_local") {
+		t.Fatalf("derived names should not be saved:\n%s", out)
+	}
+	if !strings.Contains(out, "name: pinned_volume") || !strings.Contains(out, "name: shared") {
+		t.Fatalf("explicit and external names should be kept:\n%s", out)
+	}
+}
@@ -11,6 +11,7 @@ type CodeSubcommand struct {
 	CodeJob    *CodeJobArgs    `arg:"subcommand:job"`
 	CodeWorker *CodeWorkerArgs `arg:"subcommand:worker"`
 	CodeCron   *CodeCronArgs   `arg:"subcommand:cron"`
+	CodeRetag  *CodeRetagArgs  `arg:"subcommand:retag"`
 }
 
 func (args *CodeSubcommand) Run() {
@@ -25,6 +26,8 @@ func (args *CodeSubcommand) Run() {
 		args.CodeWorker.Run()
 	case args.CodeCron != nil:
 		args.CodeCron.Run()
+	case args.CodeRetag != nil:
+		args.CodeRetag.Run()
 
 	default:
 		log.Fatal(errors.New("command not supported"))
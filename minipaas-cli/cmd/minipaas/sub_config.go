@@ -7,12 +7,27 @@ import (
 
 type ConfigSubcommand struct {
 	ConfigCreate *ConfigCreateArgs `arg:"subcommand:create"`
+	ConfigDelete *ConfigDeleteArgs `arg:"subcommand:delete"`
+	ConfigPrune  *ConfigPruneArgs  `arg:"subcommand:prune"`
+	ConfigList   *ConfigListArgs   `arg:"subcommand:list"`
+	ConfigRotate *ConfigRotateArgs `arg:"subcommand:rotate"`
+	ConfigDetach *ConfigDetachArgs `arg:"subcommand:detach"`
 }
 
 func (args *ConfigSubcommand) Run() {
 	switch {
 	case args.ConfigCreate != nil:
 		args.ConfigCreate.Run()
+	case args.ConfigDelete != nil:
+		args.ConfigDelete.Run()
+	case args.ConfigPrune != nil:
+		args.ConfigPrune.Run()
+	case args.ConfigList != nil:
+		args.ConfigList.Run()
+	case args.ConfigRotate != nil:
+		args.ConfigRotate.Run()
+	case args.ConfigDetach != nil:
+		args.ConfigDetach.Run()
 
 	default:
 		log.Fatal(errors.New("command not supported"))
@@ -6,22 +6,43 @@ import (
 )
 
 type DeploySubcommand struct {
-	DeployBuild   *DeployBuildArgs   `arg:"subcommand:build"`
-	DeployRollout *DeployRolloutArgs `arg:"subcommand:rollout"`
-	DeployCanary  *DeployCanaryArgs  `arg:"subcommand:canary"`
-	DeployRouting *DeployRoutingArgs `arg:"subcommand:routing"`
+	DeployBuild     *DeployBuildArgs           `arg:"subcommand:build"`
+	DeployPush      *DeployPushArgs            `arg:"subcommand:push"`
+	DeployDiff      *DeployDiffArgs            `arg:"subcommand:diff"`
+	DeployRollout   *DeployRolloutArgs         `arg:"subcommand:rollout"`
+	DeployCanary    *DeployCanarySubcommand    `arg:"subcommand:canary"`
+	DeployRouting   *DeployRoutingArgs         `arg:"subcommand:routing"`
+	DeployVersion   *DeployVersionSubcommand   `arg:"subcommand:version"`
+	DeployHistory   *DeployHistoryArgs         `arg:"subcommand:history"`
+	DeployRollback  *DeployRollbackArgs        `arg:"subcommand:rollback"`
+	DeployBluegreen *DeployBluegreenSubcommand `arg:"subcommand:bluegreen"`
+	DeployJob       *DeployJobSubcommand       `arg:"subcommand:job"`
 }
 
 func (args *DeploySubcommand) Run() {
 	switch {
+	case args.DeployDiff != nil:
+		args.DeployDiff.Run()
 	case args.DeployRollout != nil:
 		args.DeployRollout.Run()
 	case args.DeployBuild != nil:
 		args.DeployBuild.Run()
+	case args.DeployPush != nil:
+		args.DeployPush.Run()
 	case args.DeployRouting != nil:
 		args.DeployRouting.Run()
 	case args.DeployCanary != nil:
 		args.DeployCanary.Run()
+	case args.DeployVersion != nil:
+		args.DeployVersion.Run()
+	case args.DeployHistory != nil:
+		args.DeployHistory.Run()
+	case args.DeployRollback != nil:
+		args.DeployRollback.Run()
+	case args.DeployBluegreen != nil:
+		args.DeployBluegreen.Run()
+	case args.DeployJob != nil:
+		args.DeployJob.Run()
 
 	default:
 		log.Fatal(errors.New("command not supported"))
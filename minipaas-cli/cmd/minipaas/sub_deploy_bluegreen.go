@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+type DeployBluegreenSubcommand struct {
+	DeployBluegreenStart  *DeployBluegreenStartArgs  `arg:"subcommand:start"`
+	DeployBluegreenSwitch *DeployBluegreenSwitchArgs `arg:"subcommand:switch"`
+	DeployBluegreenFinish *DeployBluegreenFinishArgs `arg:"subcommand:finish"`
+}
+
+func (args *DeployBluegreenSubcommand) Run() {
+	switch {
+	case args.DeployBluegreenStart != nil:
+		args.DeployBluegreenStart.Run()
+	case args.DeployBluegreenSwitch != nil:
+		args.DeployBluegreenSwitch.Run()
+	case args.DeployBluegreenFinish != nil:
+		args.DeployBluegreenFinish.Run()
+
+	default:
+		log.Fatal(errors.New("command not supported"))
+	}
+
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+type DeployCanarySubcommand struct {
+	DeployCanaryStart   *DeployCanaryStartArgs   `arg:"subcommand:start"`
+	DeployCanaryPromote *DeployCanaryPromoteArgs `arg:"subcommand:promote"`
+	DeployCanaryAbort   *DeployCanaryAbortArgs   `arg:"subcommand:abort"`
+}
+
+func (args *DeployCanarySubcommand) Run() {
+	switch {
+	case args.DeployCanaryStart != nil:
+		args.DeployCanaryStart.Run()
+	case args.DeployCanaryPromote != nil:
+		args.DeployCanaryPromote.Run()
+	case args.DeployCanaryAbort != nil:
+		args.DeployCanaryAbort.Run()
+
+	default:
+		log.Fatal(errors.New("command not supported"))
+	}
+
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+type DeployJobSubcommand struct {
+	DeployJobRun *DeployJobRunArgs `arg:"subcommand:run"`
+}
+
+func (args *DeployJobSubcommand) Run() {
+	switch {
+	case args.DeployJobRun != nil:
+		args.DeployJobRun.Run()
+
+	default:
+		log.Fatal(errors.New("command not supported"))
+	}
+
+}
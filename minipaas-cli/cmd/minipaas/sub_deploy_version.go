@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+type DeployVersionSubcommand struct {
+	DeployVersionBump *DeployVersionBumpArgs `arg:"subcommand:bump"`
+	DeployVersionShow *DeployVersionShowArgs `arg:"subcommand:show"`
+}
+
+func (args *DeployVersionSubcommand) Run() {
+	switch {
+	case args.DeployVersionBump != nil:
+		args.DeployVersionBump.Run()
+	case args.DeployVersionShow != nil:
+		args.DeployVersionShow.Run()
+
+	default:
+		log.Fatal(errors.New("command not supported"))
+	}
+
+}
@@ -7,12 +7,39 @@ import (
 
 type SecretSubcommand struct {
 	SecretCreate *SecretCreateArgs `arg:"subcommand:create"`
+	SecretDelete *SecretDeleteArgs `arg:"subcommand:delete"`
+	SecretPrune  *SecretPruneArgs  `arg:"subcommand:prune"`
+	SecretList   *SecretListArgs   `arg:"subcommand:list"`
+	SecretRotate *SecretRotateArgs `arg:"subcommand:rotate"`
+	SecretDetach *SecretDetachArgs `arg:"subcommand:detach"`
+	SecretSeal   *SecretSealArgs   `arg:"subcommand:seal"`
+	SecretUnseal *SecretUnsealArgs `arg:"subcommand:unseal"`
+	SecretSync   *SecretSyncArgs   `arg:"subcommand:sync"`
+	SecretImport *SecretImportArgs `arg:"subcommand:import"`
 }
 
 func (args *SecretSubcommand) Run() {
 	switch {
 	case args.SecretCreate != nil:
 		args.SecretCreate.Run()
+	case args.SecretDelete != nil:
+		args.SecretDelete.Run()
+	case args.SecretPrune != nil:
+		args.SecretPrune.Run()
+	case args.SecretList != nil:
+		args.SecretList.Run()
+	case args.SecretRotate != nil:
+		args.SecretRotate.Run()
+	case args.SecretDetach != nil:
+		args.SecretDetach.Run()
+	case args.SecretSeal != nil:
+		args.SecretSeal.Run()
+	case args.SecretUnseal != nil:
+		args.SecretUnseal.Run()
+	case args.SecretSync != nil:
+		args.SecretSync.Run()
+	case args.SecretImport != nil:
+		args.SecretImport.Run()
 
 	default:
 		log.Fatal(errors.New("command not supported"))
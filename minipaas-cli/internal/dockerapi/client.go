@@ -0,0 +1,216 @@
+// Package dockerapi is a small client for the Docker Engine API covering what
+// MiniPaaS needs from a Swarm manager: secrets, configs, services, tasks,
+// nodes, containers and exec.
+//
+// It talks HTTP over the local unix socket or over TCP with mutual TLS, using
+// the same DOCKER_HOST, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY variables as
+// the docker CLI, so it works with the environment set up by MiniPaaS.
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// APIVersion is the Engine API version requested. 1.41 ships with Docker 20.10
+// and covers every endpoint used here.
+const APIVersion = "1.41"
+
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Client is a Docker Engine API client. The zero value is not usable; create
+// one with New or NewFromEnv.
+type Client struct {
+	// baseURL is the scheme and host requests are sent to. For unix sockets
+	// the host is a placeholder and the dialer ignores it.
+	baseURL string
+	http    *http.Client
+	dial    func(ctx context.Context) (net.Conn, error)
+}
+
+// NewFromEnv creates a client from DOCKER_HOST and DOCKER_CERT_PATH.
+// Without DOCKER_HOST it uses the local socket. The server certificate is
+// always verified against the CA of DOCKER_CERT_PATH.
+func NewFromEnv() (*Client, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DefaultHost
+	}
+
+	var tlsConfig *tls.Config
+	if certPath := os.Getenv("DOCKER_CERT_PATH"); certPath != "" && strings.HasPrefix(host, "tcp://") {
+		var err error
+		tlsConfig, err = TLSConfig(certPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return New(host, tlsConfig)
+}
+
+// TLSConfig loads ca.pem, cert.pem and key.pem from certPath, the layout
+// produced by `minipaas certs client`. The server certificate must be
+// signed by ca.pem.
+func TLSConfig(certPath string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("load CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", filepath.Join(certPath, "ca.pem"))
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// New creates a client for host, which is unix:///path/to/socket,
+// tcp://host:port or an http(s):// URL. tlsConfig, when set, is used for TCP
+// hosts.
+func New(host string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	var dialer net.Dialer
+	c := &Client{}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.baseURL = "http://docker"
+		c.dial = func(ctx context.Context) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	case "tcp", "http", "https":
+		addr := u.Host
+		scheme := "http"
+		if tlsConfig != nil || u.Scheme == "https" {
+			scheme = "https"
+		}
+		c.baseURL = scheme + "://" + addr
+		c.dial = func(ctx context.Context) (net.Conn, error) {
+			if scheme == "https" {
+				d := tls.Dialer{NetDialer: &dialer, Config: tlsConfig}
+				return d.DialContext(ctx, "tcp", addr)
+			}
+			return dialer.DialContext(ctx, "tcp", addr)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q: use unix:// or tcp://", host)
+	}
+
+	c.http = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return c.dial(ctx)
+			},
+			DialTLSContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return c.dial(ctx)
+			},
+			IdleConnTimeout: 30 * time.Second,
+		},
+	}
+	return c, nil
+}
+
+// Close releases idle connections kept by the client.
+func (c *Client) Close() {
+	c.http.CloseIdleConnections()
+}
+
+// Filters is the `filters` query parameter: a filter name mapped to the
+// values it accepts, e.g. {"label": {"a=b"}}.
+type Filters map[string][]string
+
+func (f Filters) encode() string {
+	m := map[string]map[string]bool{}
+	for name, values := range f {
+		m[name] = map[string]bool{}
+		for _, v := range values {
+			m[name][v] = true
+		}
+	}
+	data, _ := json.Marshal(m)
+	return string(data)
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := c.baseURL + "/v" + APIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func filterQuery(filters Filters) url.Values {
+	query := url.Values{}
+	if len(filters) > 0 {
+		query.Set("filters", filters.encode())
+	}
+	return query
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response
+// into out when it is not nil. Non-2xx responses become an *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &ConnectionError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(method, path, resp)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// Ping checks that the daemon answers.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
@@ -0,0 +1,163 @@
+package dockerapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestClient starts an httptest stand-in for the daemon and returns a
+// client pointed at it.
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestNew_Hosts(t *testing.T) {
+	for _, host := range []string{"unix:///var/run/docker.sock", "tcp://10.0.0.1:2376", "http://127.0.0.1:2375"} {
+		if _, err := New(host, nil); err != nil {
+			t.Fatalf("New(%q): %v", host, err)
+		}
+	}
+	if _, err := New("ssh://user@host", nil); err == nil {
+		t.Fatalf("expected error for unsupported scheme")
+	}
+}
+
+func TestNewFromEnv_MissingCerts(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2376")
+	t.Setenv("DOCKER_CERT_PATH", t.TempDir())
+	t.Setenv("DOCKER_TLS_VERIFY", "1")
+	if _, err := NewFromEnv(); err == nil {
+		t.Fatalf("expected error when cert files are missing")
+	}
+}
+
+func TestTLSConfig_AlwaysVerifies(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	os.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600)
+	os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	// Without DOCKER_TLS_VERIFY the CA is still required.
+	if _, err := TLSConfig(dir); err == nil {
+		t.Fatalf("expected an error without ca.pem")
+	}
+
+	os.WriteFile(filepath.Join(dir, "ca.pem"), certPEM, 0600)
+	cfg, err := TLSConfig(dir)
+	if err != nil {
+		t.Fatalf("TLSConfig: %v", err)
+	}
+	if cfg.InsecureSkipVerify || cfg.RootCAs == nil {
+		t.Fatalf("expected the server certificate to be verified: %#v", cfg)
+	}
+}
+
+func TestFiltersEncode(t *testing.T) {
+	got := Filters{"label": {"a=b"}}.encode()
+	if got != `{"label":{"a=b":true}}` {
+		t.Fatalf("filters mismatch: %s", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v" + APIVersion + "/secrets/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"secret missing not found"}`))
+		case "/v" + APIVersion + "/secrets/used":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"secret is in use"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"message":"This node is not a swarm manager."}`))
+		}
+	}))
+	ctx := context.Background()
+
+	_, err := c.SecretInspect(ctx, "missing")
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Message != "secret missing not found" {
+		t.Fatalf("expected *Error with daemon message, got %#v", err)
+	}
+
+	if err = c.SecretRemove(ctx, "used"); !IsConflict(err) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if _, err = c.NodeList(ctx, nil); !errors.Is(err, ErrNotSwarmManager) {
+		t.Fatalf("expected not swarm manager, got %v", err)
+	}
+}
+
+func TestConnectionError(t *testing.T) {
+	c, err := New("unix://"+filepath.Join(t.TempDir(), "missing.sock"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Ping(context.Background())
+	var connErr *ConnectionError
+	if !errors.As(err, &connErr) {
+		t.Fatalf("expected *ConnectionError, got %v", err)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := &httptest.Server{Listener: l, Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v"+APIVersion+"/_ping" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("OK"))
+	})}}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := New("unix://"+socket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Config struct {
+	ID        string     `json:"ID"`
+	Version   Version    `json:"Version"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	Spec      ConfigSpec `json:"Spec"`
+}
+
+type ConfigSpec struct {
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels,omitempty"`
+	Data   []byte            `json:"Data,omitempty"`
+}
+
+// ConfigList returns the configs matching filters (id, label, name, names).
+func (c *Client) ConfigList(ctx context.Context, filters Filters) ([]Config, error) {
+	var configs []Config
+	err := c.do(ctx, http.MethodGet, "/configs", filterQuery(filters), nil, &configs)
+	return configs, err
+}
+
+// ConfigInspect returns the config with the given ID or name, including its
+// data.
+func (c *Client) ConfigInspect(ctx context.Context, idOrName string) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodGet, "/configs/"+url.PathEscape(idOrName), nil, nil, &config)
+	return config, err
+}
+
+// ConfigCreate creates a config and returns its ID.
+func (c *Client) ConfigCreate(ctx context.Context, spec ConfigSpec) (string, error) {
+	var resp createResponse
+	err := c.do(ctx, http.MethodPost, "/configs/create", nil, spec, &resp)
+	return resp.ID, err
+}
+
+// ConfigRemove removes the config with the given ID or name.
+func (c *Client) ConfigRemove(ctx context.Context, idOrName string) error {
+	return c.do(ctx, http.MethodDelete, "/configs/"+url.PathEscape(idOrName), nil, nil, nil)
+}
@@ -0,0 +1,28 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+)
+
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerList returns the running containers of the node the client is
+// connected to that match filters (label, name, status, ...). With all set,
+// stopped containers are included.
+func (c *Client) ContainerList(ctx context.Context, all bool, filters Filters) ([]Container, error) {
+	query := filterQuery(filters)
+	if all {
+		query.Set("all", "1")
+	}
+	var containers []Container
+	err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, &containers)
+	return containers, err
+}
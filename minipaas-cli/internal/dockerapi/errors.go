@@ -0,0 +1,84 @@
+package dockerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotFound matches an *Error for a missing object.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches an *Error for a name already in use or an object
+	// still in use, e.g. removing a secret mounted by a service.
+	ErrConflict = errors.New("conflict")
+	// ErrNotSwarmManager matches an *Error returned when the node is not a
+	// swarm manager.
+	ErrNotSwarmManager = errors.New("node is not a swarm manager")
+)
+
+// Error is a non-2xx answer from the daemon.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("docker API %s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is lets errors.Is match the sentinel errors by status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrNotSwarmManager:
+		return e.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+func newError(method, path string, resp *http.Response) *Error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Message string `json:"message"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		msg = body.Message
+	}
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return &Error{Method: method, Path: path, StatusCode: resp.StatusCode, Message: msg}
+}
+
+// ConnectionError means the daemon could not be reached at all.
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("cannot connect to the docker daemon: %v", e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound reports whether err is a not found answer from the daemon.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err is a conflict answer from the daemon.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
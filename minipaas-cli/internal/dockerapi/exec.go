@@ -0,0 +1,159 @@
+package dockerapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+type ExecConfig struct {
+	Cmd          []string `json:"Cmd"`
+	Env          []string `json:"Env,omitempty"`
+	WorkingDir   string   `json:"WorkingDir,omitempty"`
+	User         string   `json:"User,omitempty"`
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+	Tty          bool     `json:"Tty"`
+}
+
+type ExecInspect struct {
+	ID          string `json:"ID"`
+	ContainerID string `json:"ContainerID"`
+	Running     bool   `json:"Running"`
+	ExitCode    int    `json:"ExitCode"`
+}
+
+// ExecCreate prepares cmd to run in a container and returns the exec ID.
+func (c *Client) ExecCreate(ctx context.Context, containerID string, cfg ExecConfig) (string, error) {
+	var resp createResponse
+	err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(containerID)+"/exec", nil, cfg, &resp)
+	return resp.ID, err
+}
+
+// ExecInspect returns the state of an exec, including its exit code once it
+// is no longer running.
+func (c *Client) ExecInspect(ctx context.Context, execID string) (ExecInspect, error) {
+	var inspect ExecInspect
+	err := c.do(ctx, http.MethodGet, "/exec/"+url.PathEscape(execID)+"/json", nil, nil, &inspect)
+	return inspect, err
+}
+
+// ExecStart starts an exec created without a TTY and streams its output to
+// stdout and stderr until it ends. stdin, when set, is copied to the process
+// and closed once drained. Nil writers discard the stream.
+func (c *Client) ExecStart(ctx context.Context, execID string, stdin io.Reader, stdout, stderr io.Writer) error {
+	path := "/exec/" + url.PathEscape(execID) + "/start"
+	body, err := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return &ConnectionError{Err: err}
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(path, nil), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err = req.Write(conn); err != nil {
+		return &ConnectionError{Err: err}
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return &ConnectionError{Err: err}
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return newError(http.MethodPost, path, resp)
+	}
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(conn, stdin)
+			if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+				_ = cw.CloseWrite()
+			}
+		}()
+	}
+
+	if err = demuxStream(br, stdout, stderr); err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Exec runs cmd in a container, streams its output and returns its exit
+// code.
+func (c *Client) Exec(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	execID, err := c.ExecCreate(ctx, containerID, ExecConfig{
+		Cmd:          cmd,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return -1, err
+	}
+	if err = c.ExecStart(ctx, execID, stdin, stdout, stderr); err != nil {
+		return -1, err
+	}
+	inspect, err := c.ExecInspect(ctx, execID)
+	if err != nil {
+		return -1, err
+	}
+	return inspect.ExitCode, nil
+}
+
+// demuxStream splits the multiplexed stream used by attach, exec and logs
+// when no TTY is allocated: each frame is an 8 byte header holding the
+// stream (1 stdout, 2 stderr) and the big-endian payload size.
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		var w io.Writer
+		switch header[0] {
+		case 0, 1:
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			return fmt.Errorf("invalid stream id %d in multiplexed output", header[0])
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDemuxStream(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(1, "out1\n"))
+	stream.Write(frame(2, "err\n"))
+	stream.Write(frame(1, "out2\n"))
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxStream(&stream, &stdout, &stderr); err != nil {
+		t.Fatalf("demuxStream: %v", err)
+	}
+	if stdout.String() != "out1\nout2\n" || stderr.String() != "err\n" {
+		t.Fatalf("demux mismatch: stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+
+	if err := demuxStream(bytes.NewReader(frame(7, "x")), nil, nil); err == nil {
+		t.Fatalf("expected error for invalid stream id")
+	}
+}
+
+func TestExec(t *testing.T) {
+	var gotStdin string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v" + APIVersion + "/containers/c1/exec":
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id":"e1"}`)
+		case "POST /v" + APIVersion + "/exec/e1/start":
+			if r.Header.Get("Upgrade") != "tcp" {
+				t.Errorf("missing upgrade header")
+			}
+			if body, _ := io.ReadAll(r.Body); !strings.Contains(string(body), `"Detach":false`) {
+				t.Errorf("start body mismatch: %s", body)
+			}
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("hijack: %v", err)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+			in, _ := io.ReadAll(buf)
+			gotStdin = string(in)
+			conn.Write(frame(1, "hello "+strings.TrimSpace(gotStdin)+"\n"))
+			conn.Write(frame(2, "warn\n"))
+		case "GET /v" + APIVersion + "/exec/e1/json":
+			io.WriteString(w, `{"ID":"e1","Running":false,"ExitCode":3}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	var stdout, stderr bytes.Buffer
+	code, err := c.Exec(context.Background(), "c1", []string{"cat"}, strings.NewReader("world\n"), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if code != 3 {
+		t.Fatalf("exit code mismatch: %d", code)
+	}
+	if gotStdin != "world\n" || stdout.String() != "hello world\n" || stderr.String() != "warn\n" {
+		t.Fatalf("streams mismatch: stdin=%q stdout=%q stderr=%q", gotStdin, stdout.String(), stderr.String())
+	}
+}
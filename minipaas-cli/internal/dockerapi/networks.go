@@ -0,0 +1,22 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+)
+
+type Network struct {
+	ID     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Driver string            `json:"Driver"`
+	Scope  string            `json:"Scope"`
+	Labels map[string]string `json:"Labels,omitempty"`
+}
+
+// NetworkList returns the networks matching filters (driver, id, label,
+// name, scope, type).
+func (c *Client) NetworkList(ctx context.Context, filters Filters) ([]Network, error) {
+	var networks []Network
+	err := c.do(ctx, http.MethodGet, "/networks", filterQuery(filters), nil, &networks)
+	return networks, err
+}
@@ -0,0 +1,28 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestNetworkList(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method+" "+r.URL.Path != "GET /v"+APIVersion+"/networks" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("filters") != `{"scope":{"swarm":true}}` {
+			t.Errorf("filters mismatch: %s", r.URL.Query().Get("filters"))
+		}
+		w.Write([]byte(`[{"Id":"n1","Name":"minipaas_default","Driver":"overlay","Scope":"swarm"}]`))
+	}))
+
+	networks, err := c.NetworkList(context.Background(), Filters{"scope": {"swarm"}})
+	if err != nil {
+		t.Fatalf("NetworkList: %v", err)
+	}
+	if len(networks) != 1 || networks[0].ID != "n1" || networks[0].Name != "minipaas_default" {
+		t.Fatalf("networks mismatch: %#v", networks)
+	}
+}
@@ -0,0 +1,61 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type Node struct {
+	ID            string          `json:"ID"`
+	Version       Version         `json:"Version"`
+	Spec          NodeSpec        `json:"Spec"`
+	Description   NodeDescription `json:"Description"`
+	Status        NodeStatus      `json:"Status"`
+	ManagerStatus *ManagerStatus  `json:"ManagerStatus,omitempty"`
+}
+
+type NodeSpec struct {
+	Name         string            `json:"Name,omitempty"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	Role         string            `json:"Role"`
+	Availability string            `json:"Availability"`
+}
+
+type NodeDescription struct {
+	Hostname string `json:"Hostname"`
+	Platform struct {
+		Architecture string `json:"Architecture"`
+		OS           string `json:"OS"`
+	} `json:"Platform"`
+	Engine struct {
+		EngineVersion string `json:"EngineVersion"`
+	} `json:"Engine"`
+}
+
+type NodeStatus struct {
+	State   string `json:"State"`
+	Message string `json:"Message,omitempty"`
+	Addr    string `json:"Addr"`
+}
+
+type ManagerStatus struct {
+	Leader       bool   `json:"Leader,omitempty"`
+	Reachability string `json:"Reachability"`
+	Addr         string `json:"Addr"`
+}
+
+// NodeList returns the nodes matching filters (id, label, membership, name,
+// node.label, role).
+func (c *Client) NodeList(ctx context.Context, filters Filters) ([]Node, error) {
+	var nodes []Node
+	err := c.do(ctx, http.MethodGet, "/nodes", filterQuery(filters), nil, &nodes)
+	return nodes, err
+}
+
+// NodeInspect returns the node with the given ID or name.
+func (c *Client) NodeInspect(ctx context.Context, idOrName string) (Node, error) {
+	var node Node
+	err := c.do(ctx, http.MethodGet, "/nodes/"+url.PathEscape(idOrName), nil, nil, &node)
+	return node, err
+}
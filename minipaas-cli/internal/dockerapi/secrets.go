@@ -0,0 +1,57 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Version struct {
+	Index uint64 `json:"Index"`
+}
+
+type Secret struct {
+	ID        string     `json:"ID"`
+	Version   Version    `json:"Version"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	Spec      SecretSpec `json:"Spec"`
+}
+
+type SecretSpec struct {
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels,omitempty"`
+	// Data is only sent on create; the daemon never returns secret data.
+	Data []byte `json:"Data,omitempty"`
+}
+
+type createResponse struct {
+	ID string `json:"ID"`
+}
+
+// SecretList returns the secrets matching filters (id, label, name, names).
+func (c *Client) SecretList(ctx context.Context, filters Filters) ([]Secret, error) {
+	var secrets []Secret
+	err := c.do(ctx, http.MethodGet, "/secrets", filterQuery(filters), nil, &secrets)
+	return secrets, err
+}
+
+// SecretInspect returns the secret with the given ID or name.
+func (c *Client) SecretInspect(ctx context.Context, idOrName string) (Secret, error) {
+	var secret Secret
+	err := c.do(ctx, http.MethodGet, "/secrets/"+url.PathEscape(idOrName), nil, nil, &secret)
+	return secret, err
+}
+
+// SecretCreate creates a secret and returns its ID.
+func (c *Client) SecretCreate(ctx context.Context, spec SecretSpec) (string, error) {
+	var resp createResponse
+	err := c.do(ctx, http.MethodPost, "/secrets/create", nil, spec, &resp)
+	return resp.ID, err
+}
+
+// SecretRemove removes the secret with the given ID or name.
+func (c *Client) SecretRemove(ctx context.Context, idOrName string) error {
+	return c.do(ctx, http.MethodDelete, "/secrets/"+url.PathEscape(idOrName), nil, nil, nil)
+}
@@ -0,0 +1,76 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	var created SecretSpec
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v" + APIVersion + "/secrets":
+			if r.URL.Query().Get("filters") != `{"name":{"db":true}}` {
+				t.Errorf("filters mismatch: %s", r.URL.Query().Get("filters"))
+			}
+			w.Write([]byte(`[{"ID":"s1","Version":{"Index":3},"Spec":{"Name":"db.267da420"}}]`))
+		case "POST /v" + APIVersion + "/secrets/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ID":"s2"}`))
+		case "DELETE /v" + APIVersion + "/secrets/db.267da420":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	secrets, err := c.SecretList(ctx, Filters{"name": {"db"}})
+	if err != nil {
+		t.Fatalf("SecretList: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Spec.Name != "db.267da420" || secrets[0].Version.Index != 3 {
+		t.Fatalf("secrets mismatch: %#v", secrets)
+	}
+
+	id, err := c.SecretCreate(ctx, SecretSpec{Name: "db.0123abcd", Data: []byte("s3cret")})
+	if err != nil || id != "s2" {
+		t.Fatalf("SecretCreate = %q, %v", id, err)
+	}
+	if created.Name != "db.0123abcd" || string(created.Data) != "s3cret" {
+		t.Fatalf("created spec mismatch: %#v", created)
+	}
+
+	if err = c.SecretRemove(ctx, "db.267da420"); err != nil {
+		t.Fatalf("SecretRemove: %v", err)
+	}
+}
+
+func TestConfigs(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v" + APIVersion + "/configs/app.conf":
+			w.Write([]byte(`{"ID":"c1","Spec":{"Name":"app.conf","Data":"aGVsbG8="}}`))
+		case "POST /v" + APIVersion + "/configs/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ID":"c2"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	cfg, err := c.ConfigInspect(ctx, "app.conf")
+	if err != nil || string(cfg.Spec.Data) != "hello" {
+		t.Fatalf("ConfigInspect = %#v, %v", cfg, err)
+	}
+	if id, err := c.ConfigCreate(ctx, ConfigSpec{Name: "app.conf.0123abcd", Data: []byte("x")}); err != nil || id != "c2" {
+		t.Fatalf("ConfigCreate = %q, %v", id, err)
+	}
+	if err = c.ConfigRemove(ctx, "missing"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Update states reported in Service.UpdateStatus.
+const (
+	UpdateStateUpdating          = "updating"
+	UpdateStatePaused            = "paused"
+	UpdateStateCompleted         = "completed"
+	UpdateStateRollbackStarted   = "rollback_started"
+	UpdateStateRollbackPaused    = "rollback_paused"
+	UpdateStateRollbackCompleted = "rollback_completed"
+)
+
+// Restart conditions of RestartPolicy.
+const (
+	RestartConditionNone      = "none"
+	RestartConditionOnFailure = "on-failure"
+	RestartConditionAny       = "any"
+)
+
+type Service struct {
+	ID           string        `json:"ID"`
+	Version      Version       `json:"Version"`
+	CreatedAt    time.Time     `json:"CreatedAt"`
+	UpdatedAt    time.Time     `json:"UpdatedAt"`
+	Spec         ServiceSpec   `json:"Spec"`
+	PreviousSpec *ServiceSpec  `json:"PreviousSpec,omitempty"`
+	UpdateStatus *UpdateStatus `json:"UpdateStatus,omitempty"`
+
+	// RawSpec is the spec exactly as returned by the daemon, including the
+	// fields ServiceSpec does not model. Updates should start from it so no
+	// setting is dropped.
+	RawSpec json.RawMessage `json:"-"`
+}
+
+func (s *Service) UnmarshalJSON(data []byte) error {
+	type plain Service
+	aux := struct {
+		*plain
+		RawSpec json.RawMessage `json:"Spec"`
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.RawSpec = aux.RawSpec
+	if len(aux.RawSpec) == 0 {
+		return nil
+	}
+	return json.Unmarshal(aux.RawSpec, &s.Spec)
+}
+
+// ServiceSpec is the subset of a service spec MiniPaaS reads.
+type ServiceSpec struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	TaskTemplate TaskSpec          `json:"TaskTemplate"`
+	Mode         ServiceMode       `json:"Mode"`
+}
+
+type TaskSpec struct {
+	ContainerSpec ContainerSpec             `json:"ContainerSpec"`
+	Resources     *ResourceRequirements     `json:"Resources,omitempty"`
+	Networks      []NetworkAttachmentConfig `json:"Networks,omitempty"`
+	RestartPolicy *RestartPolicy            `json:"RestartPolicy,omitempty"`
+	ForceUpdate   uint64                    `json:"ForceUpdate,omitempty"`
+}
+
+type RestartPolicy struct {
+	Condition string `json:"Condition,omitempty"`
+}
+
+type ResourceRequirements struct {
+	Limits       *Limit     `json:"Limits,omitempty"`
+	Reservations *Resources `json:"Reservations,omitempty"`
+}
+
+type Limit struct {
+	NanoCPUs    int64 `json:"NanoCPUs,omitempty"`
+	MemoryBytes int64 `json:"MemoryBytes,omitempty"`
+	Pids        int64 `json:"Pids,omitempty"`
+}
+
+type Resources struct {
+	NanoCPUs    int64 `json:"NanoCPUs,omitempty"`
+	MemoryBytes int64 `json:"MemoryBytes,omitempty"`
+}
+
+// NetworkAttachmentConfig attaches a service to a network; Target is the
+// network ID once the service is created.
+type NetworkAttachmentConfig struct {
+	Target  string   `json:"Target"`
+	Aliases []string `json:"Aliases,omitempty"`
+}
+
+type ContainerSpec struct {
+	Image       string            `json:"Image"`
+	Labels      map[string]string `json:"Labels,omitempty"`
+	Env         []string          `json:"Env,omitempty"`
+	Healthcheck *HealthConfig     `json:"Healthcheck,omitempty"`
+	Secrets     []SecretReference `json:"Secrets,omitempty"`
+	Configs     []ConfigReference `json:"Configs,omitempty"`
+}
+
+// HealthConfig is the healthcheck of a container. A Test of ["NONE"]
+// disables the healthcheck of the image.
+type HealthConfig struct {
+	Test []string `json:"Test,omitempty"`
+}
+
+type FileTarget struct {
+	Name string `json:"Name"`
+	UID  string `json:"UID"`
+	GID  string `json:"GID"`
+	Mode uint32 `json:"Mode"`
+}
+
+type SecretReference struct {
+	File       *FileTarget `json:"File,omitempty"`
+	SecretID   string      `json:"SecretID"`
+	SecretName string      `json:"SecretName"`
+}
+
+type ConfigReference struct {
+	File       *FileTarget `json:"File,omitempty"`
+	ConfigID   string      `json:"ConfigID"`
+	ConfigName string      `json:"ConfigName"`
+}
+
+type ServiceMode struct {
+	Replicated    *ReplicatedService `json:"Replicated,omitempty"`
+	Global        *struct{}          `json:"Global,omitempty"`
+	ReplicatedJob *ReplicatedJob     `json:"ReplicatedJob,omitempty"`
+	GlobalJob     *struct{}          `json:"GlobalJob,omitempty"`
+}
+
+type ReplicatedJob struct {
+	MaxConcurrent    *uint64 `json:"MaxConcurrent,omitempty"`
+	TotalCompletions *uint64 `json:"TotalCompletions,omitempty"`
+}
+
+type ReplicatedService struct {
+	Replicas *uint64 `json:"Replicas,omitempty"`
+}
+
+type UpdateStatus struct {
+	State       string     `json:"State"`
+	StartedAt   *time.Time `json:"StartedAt,omitempty"`
+	CompletedAt *time.Time `json:"CompletedAt,omitempty"`
+	Message     string     `json:"Message"`
+}
+
+type serviceUpdateResponse struct {
+	Warnings []string `json:"Warnings"`
+}
+
+// ServiceList returns the services matching filters (id, label, mode, name).
+func (c *Client) ServiceList(ctx context.Context, filters Filters) ([]Service, error) {
+	var services []Service
+	err := c.do(ctx, http.MethodGet, "/services", filterQuery(filters), nil, &services)
+	return services, err
+}
+
+// ServiceInspect returns the service with the given ID or name.
+func (c *Client) ServiceInspect(ctx context.Context, idOrName string) (Service, error) {
+	var service Service
+	err := c.do(ctx, http.MethodGet, "/services/"+url.PathEscape(idOrName), nil, nil, &service)
+	return service, err
+}
+
+// ServiceCreate creates a service from spec, a ServiceSpec or a raw spec,
+// and returns its ID.
+func (c *Client) ServiceCreate(ctx context.Context, spec any) (string, error) {
+	var resp createResponse
+	err := c.do(ctx, http.MethodPost, "/services/create", nil, spec, &resp)
+	return resp.ID, err
+}
+
+// ServiceUpdate replaces the spec of a service. version must be the
+// Version.Index the spec was read at; a stale version fails with a conflict.
+// The daemon's warnings are returned.
+func (c *Client) ServiceUpdate(ctx context.Context, idOrName string, version uint64, spec any) ([]string, error) {
+	query := url.Values{}
+	query.Set("version", strconv.FormatUint(version, 10))
+	var resp serviceUpdateResponse
+	err := c.do(ctx, http.MethodPost, "/services/"+url.PathEscape(idOrName)+"/update", query, spec, &resp)
+	return resp.Warnings, err
+}
+
+// ServiceRemove removes the service with the given ID or name.
+func (c *Client) ServiceRemove(ctx context.Context, idOrName string) error {
+	return c.do(ctx, http.MethodDelete, "/services/"+url.PathEscape(idOrName), nil, nil, nil)
+}
+
+// ServiceLogs streams the output of the tasks of a service, without a TTY,
+// to stdout and stderr. With follow, it keeps streaming until ctx is done.
+func (c *Client) ServiceLogs(ctx context.Context, idOrName string, follow bool, stdout, stderr io.Writer) error {
+	path := "/services/" + url.PathEscape(idOrName) + "/logs"
+	query := url.Values{}
+	query.Set("stdout", "1")
+	query.Set("stderr", "1")
+	if follow {
+		query.Set("follow", "1")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path, query), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &ConnectionError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(http.MethodGet, path, resp)
+	}
+
+	if err = demuxStream(resp.Body, stdout, stderr); err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
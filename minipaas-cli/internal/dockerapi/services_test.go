@@ -0,0 +1,136 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const serviceJSON = `{
+  "ID": "svc1",
+  "Version": {"Index": 42},
+  "Spec": {
+    "Name": "minipaas_api",
+    "Labels": {"com.docker.stack.namespace": "minipaas"},
+    "TaskTemplate": {
+      "ContainerSpec": {
+        "Image": "registry/api:1.0.0",
+        "Secrets": [{"File": {"Name": "db", "UID": "0", "GID": "0", "Mode": 292}, "SecretID": "s1", "SecretName": "db.267da420"}]
+      },
+      "RestartPolicy": {"Condition": "any"}
+    },
+    "Mode": {"Replicated": {"Replicas": 2}}
+  },
+  "UpdateStatus": {"State": "rollback_completed", "Message": "rollback completed"}
+}`
+
+func TestServiceInspectAndUpdate(t *testing.T) {
+	var gotVersion string
+	var gotSpec map[string]any
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v" + APIVersion + "/services/minipaas_api":
+			io.WriteString(w, serviceJSON)
+		case "POST /v" + APIVersion + "/services/svc1/update":
+			gotVersion = r.URL.Query().Get("version")
+			json.NewDecoder(r.Body).Decode(&gotSpec)
+			io.WriteString(w, `{"Warnings":["image could not be accessed"]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	svc, err := c.ServiceInspect(ctx, "minipaas_api")
+	if err != nil {
+		t.Fatalf("ServiceInspect: %v", err)
+	}
+	if svc.Spec.Name != "minipaas_api" || svc.Spec.TaskTemplate.ContainerSpec.Image != "registry/api:1.0.0" {
+		t.Fatalf("spec mismatch: %#v", svc.Spec)
+	}
+	if secs := svc.Spec.TaskTemplate.ContainerSpec.Secrets; len(secs) != 1 || secs[0].SecretName != "db.267da420" || secs[0].File.Mode != 0444 {
+		t.Fatalf("secrets mismatch: %#v", secs)
+	}
+	if svc.Spec.Mode.Replicated == nil || *svc.Spec.Mode.Replicated.Replicas != 2 {
+		t.Fatalf("mode mismatch: %#v", svc.Spec.Mode)
+	}
+	if svc.UpdateStatus == nil || svc.UpdateStatus.State != UpdateStateRollbackCompleted {
+		t.Fatalf("update status mismatch: %#v", svc.UpdateStatus)
+	}
+
+	// Updating from RawSpec keeps fields the typed spec does not model.
+	warnings, err := c.ServiceUpdate(ctx, svc.ID, svc.Version.Index, svc.RawSpec)
+	if err != nil {
+		t.Fatalf("ServiceUpdate: %v", err)
+	}
+	if len(warnings) != 1 || gotVersion != "42" {
+		t.Fatalf("update mismatch: warnings=%v version=%s", warnings, gotVersion)
+	}
+	tmpl := gotSpec["TaskTemplate"].(map[string]any)
+	if _, ok := tmpl["RestartPolicy"]; !ok {
+		t.Fatalf("raw spec lost RestartPolicy: %#v", gotSpec)
+	}
+}
+
+func TestTasksAndNodes(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v" + APIVersion + "/tasks":
+			if r.URL.Query().Get("filters") != `{"service":{"minipaas_api":true}}` {
+				t.Errorf("filters mismatch: %s", r.URL.Query().Get("filters"))
+			}
+			io.WriteString(w, `[{"ID":"t1","ServiceID":"svc1","NodeID":"n1","Slot":1,"DesiredState":"shutdown",
+				"Status":{"State":"failed","Err":"task: non-zero exit (1)","ContainerStatus":{"ContainerID":"c1","ExitCode":1}}}]`)
+		case "/v" + APIVersion + "/nodes":
+			io.WriteString(w, `[{"ID":"n1","Spec":{"Role":"manager","Availability":"active"},"Description":{"Hostname":"node-1"},
+				"Status":{"State":"ready","Addr":"10.0.0.1"},"ManagerStatus":{"Leader":true,"Reachability":"reachable"}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	tasks, err := c.TaskList(ctx, Filters{"service": {"minipaas_api"}})
+	if err != nil {
+		t.Fatalf("TaskList: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Status.State != TaskStateFailed || tasks[0].Status.ContainerStatus.ExitCode != 1 {
+		t.Fatalf("tasks mismatch: %#v", tasks)
+	}
+
+	nodes, err := c.NodeList(ctx, nil)
+	if err != nil {
+		t.Fatalf("NodeList: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Description.Hostname != "node-1" || !nodes[0].ManagerStatus.Leader {
+		t.Fatalf("nodes mismatch: %#v", nodes)
+	}
+}
+
+func TestServiceLogs(t *testing.T) {
+	var query string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v"+APIVersion+"/services/minipaas_migrate_job/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write(frame(1, "migrating\n"))
+		w.Write(frame(2, "warning\n"))
+		w.Write(frame(1, "done\n"))
+	}))
+
+	var stdout, stderr strings.Builder
+	if err := c.ServiceLogs(context.Background(), "minipaas_migrate_job", true, &stdout, &stderr); err != nil {
+		t.Fatalf("ServiceLogs: %v", err)
+	}
+	if stdout.String() != "migrating\ndone\n" || stderr.String() != "warning\n" {
+		t.Fatalf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+	if !strings.Contains(query, "follow=1") || !strings.Contains(query, "stderr=1") {
+		t.Fatalf("query = %q", query)
+	}
+}
@@ -0,0 +1,67 @@
+package dockerapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Task states, in lifecycle order, as reported in TaskStatus.State.
+const (
+	TaskStateNew       = "new"
+	TaskStatePending   = "pending"
+	TaskStateAssigned  = "assigned"
+	TaskStateAccepted  = "accepted"
+	TaskStatePreparing = "preparing"
+	TaskStateStarting  = "starting"
+	TaskStateRunning   = "running"
+	TaskStateComplete  = "complete"
+	TaskStateShutdown  = "shutdown"
+	TaskStateFailed    = "failed"
+	TaskStateRejected  = "rejected"
+	TaskStateRemove    = "remove"
+	TaskStateOrphaned  = "orphaned"
+)
+
+type Task struct {
+	ID           string     `json:"ID"`
+	Version      Version    `json:"Version"`
+	CreatedAt    time.Time  `json:"CreatedAt"`
+	UpdatedAt    time.Time  `json:"UpdatedAt"`
+	ServiceID    string     `json:"ServiceID"`
+	NodeID       string     `json:"NodeID"`
+	Slot         int        `json:"Slot"`
+	DesiredState string     `json:"DesiredState"`
+	Status       TaskStatus `json:"Status"`
+	Spec         TaskSpec   `json:"Spec"`
+}
+
+type TaskStatus struct {
+	Timestamp       time.Time        `json:"Timestamp"`
+	State           string           `json:"State"`
+	Message         string           `json:"Message"`
+	Err             string           `json:"Err,omitempty"`
+	ContainerStatus *ContainerStatus `json:"ContainerStatus,omitempty"`
+}
+
+type ContainerStatus struct {
+	ContainerID string `json:"ContainerID"`
+	PID         int    `json:"PID"`
+	ExitCode    int    `json:"ExitCode"`
+}
+
+// TaskList returns the tasks matching filters (desired-state, id, label,
+// name, node, service).
+func (c *Client) TaskList(ctx context.Context, filters Filters) ([]Task, error) {
+	var tasks []Task
+	err := c.do(ctx, http.MethodGet, "/tasks", filterQuery(filters), nil, &tasks)
+	return tasks, err
+}
+
+// TaskInspect returns the task with the given ID.
+func (c *Client) TaskInspect(ctx context.Context, id string) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, nil, &task)
+	return task, err
+}